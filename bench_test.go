@@ -0,0 +1,136 @@
+package dotbeam
+
+import (
+	"fmt"
+	"image"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/satindergrewal/dotbeam/sim"
+)
+
+// Benchmarks for each pipeline stage. BenchmarkCapture also reports dot and
+// frame error rates over simulated camera channels; cmd/dotbeam-bench
+// sweeps the same measurements across many configurations.
+
+func benchPayload(n int) []byte {
+	data := make([]byte, n)
+	rand.NewChaCha8([32]byte{1}).Read(data)
+	return data
+}
+
+func BenchmarkEncode(b *testing.B) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	cfg.ParityBytes = 4
+	data := benchPayload(1024)
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		if _, err := NewEncoder(cfg).Encode(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	cfg.ParityBytes = 4
+	data := benchPayload(1024)
+	frames, err := NewEncoder(cfg).Encode(data)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		dec := NewDecoder(cfg)
+		for _, frame := range frames {
+			if _, err := dec.AddFrame(frame.Dots); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkRenderFrame(b *testing.B) {
+	cfg := DefaultConfig()
+	frames, err := NewEncoder(cfg).Encode(benchPayload(16))
+	if err != nil {
+		b.Fatal(err)
+	}
+	layout := NewLayout(cfg, 1, 1)
+	for b.Loop() {
+		RenderFrame(frames[0], layout, 480, 480)
+	}
+}
+
+func BenchmarkDecodeImage(b *testing.B) {
+	cfg := DefaultConfig()
+	frames, err := NewEncoder(cfg).Encode(benchPayload(16))
+	if err != nil {
+		b.Fatal(err)
+	}
+	img := RenderFrame(frames[0], NewLayout(cfg, 1, 1), 480, 480)
+	for b.Loop() {
+		if _, _, err := DecodeImage(img, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCapture distorts, locates and decodes one captured frame per
+// operation, cycling through a transfer's frames with a fresh channel seed
+// each time. Time includes the simulator.
+func BenchmarkCapture(b *testing.B) {
+	channels := []struct {
+		name     string
+		strength float64
+	}{{"clean", 0}, {"phone", 0.5}, {"harsh", 1}}
+
+	for _, rings := range []int{3, 4, 5} {
+		for _, bits := range []int{2, 3} {
+			for _, ch := range channels {
+				b.Run(fmt.Sprintf("rings=%d/bits=%d/%s", rings, bits, ch.name), func(b *testing.B) {
+					cfg := DefaultConfig()
+					cfg.Rings = rings
+					cfg.BitsPerDot = bits
+					cfg.Checksum = true
+					frames, err := NewEncoder(cfg).Encode(benchPayload(128))
+					if err != nil {
+						b.Fatal(err)
+					}
+					layout := NewLayout(cfg, 1, 1)
+					images := make([]*image.RGBA, len(frames))
+					for i, frame := range frames {
+						images[i] = RenderFrame(frame, layout, 480, 480)
+					}
+
+					var dotErrors, dotsRead, frameErrors, captures int
+					for i := 0; b.Loop(); i++ {
+						n := i % len(frames)
+						img := sim.Random(uint64(i), ch.strength).Apply(images[n])
+						captures++
+						dots, _, err := DecodeImage(img, cfg)
+						if err == nil {
+							for j, dot := range dots {
+								dotsRead++
+								if dot.Value != frames[n].Dots[j].Value {
+									dotErrors++
+								}
+							}
+							// A fresh decoder checks just this frame.
+							_, err = NewDecoder(cfg).AddFrame(dots)
+						}
+						if err != nil {
+							frameErrors++
+						}
+					}
+					if dotsRead > 0 {
+						b.ReportMetric(float64(dotErrors)/float64(dotsRead), "dot-err/dot")
+					}
+					b.ReportMetric(float64(frameErrors)/float64(captures), "frame-err/frame")
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package dotbeam
+
+// checksumSize is the length of the per-frame CRC-16 trailer.
+const checksumSize = 2
+
+// crc16 computes CRC-16/CCITT-FALSE (poly 0x1021, init 0xFFFF, no
+// reflection) over data.
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// appendChecksum zero-pads frameBytes to bodySize and appends the
+// big-endian CRC-16 of the padded body.
+func appendChecksum(frameBytes []byte, bodySize int) []byte {
+	body := make([]byte, bodySize, bodySize+checksumSize)
+	copy(body, frameBytes)
+	crc := crc16(body)
+	return append(body, byte(crc>>8), byte(crc))
+}
+
+// verifyChecksum checks the CRC-16 trailer following the first bodySize
+// bytes of data and returns the body.
+func verifyChecksum(data []byte, bodySize int) ([]byte, error) {
+	if bodySize <= 0 || len(data) < bodySize+checksumSize {
+		return nil, ErrInvalidFrame
+	}
+	body := data[:bodySize]
+	want := uint16(data[bodySize])<<8 | uint16(data[bodySize+1])
+	if crc16(body) != want {
+		return nil, ErrChecksum
+	}
+	return body, nil
+}
@@ -0,0 +1,92 @@
+package dotbeam
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCRC16(t *testing.T) {
+	// Standard CRC-16/CCITT-FALSE check value.
+	if got := crc16([]byte("123456789")); got != 0x29B1 {
+		t.Fatalf("crc16(\"123456789\") = %#04x, want 0x29b1", got)
+	}
+}
+
+func TestChecksumBytesPerFrame(t *testing.T) {
+	config := DefaultConfig()
+	config.Checksum = true
+	// 22 bytes - 2 header - 2 CRC = 18
+	if got := config.BytesPerFrame(); got != 18 {
+		t.Fatalf("expected 18 bytes per frame, got %d", got)
+	}
+}
+
+func TestChecksumRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.Checksum = true
+
+	data := bytes.Repeat([]byte("checksummed "), 5)
+	if got := roundTrip(t, config, data); !bytes.HasPrefix(got, data) {
+		t.Fatalf("round-trip failed:\n got: %q\nwant prefix: %q", got, data)
+	}
+}
+
+func TestChecksumRejectsMisreadDot(t *testing.T) {
+	config := DefaultConfig()
+	config.Checksum = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	frames := mustEncode(t, enc, []byte("one bad dot"))
+	for i := range frames[0].Dots {
+		dots := append([]Dot(nil), frames[0].Dots...)
+		dots[i].Value ^= 0x02 // Orange ↔ Gold style confusion
+
+		// The final dot only carries padding bits beyond the CRC.
+		if i*config.BitsPerDot >= (config.frameBodySize()+checksumSize)*8 {
+			continue
+		}
+		if _, err := dec.AddFrame(dots); err != ErrChecksum {
+			t.Fatalf("dot %d flipped: AddFrame error = %v, want ErrChecksum", i, err)
+		}
+	}
+	if dec.Progress() != 0 {
+		t.Fatalf("corrupted frames were stored: progress = %f", dec.Progress())
+	}
+
+	// The clean read is still accepted afterwards.
+	done, err := dec.AddFrame(frames[0].Dots)
+	if err != nil || !done {
+		t.Fatalf("AddFrame(clean) = (%v, %v), want (true, nil)", done, err)
+	}
+}
+
+func TestChecksumFountain(t *testing.T) {
+	config := DefaultConfig()
+	config.Checksum = true
+	config.UseFountain = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("F"), 70)
+	frames := mustEncode(t, enc, data)
+
+	bad := append([]Dot(nil), frames[0].Dots...)
+	bad[10].Value ^= 0x01
+	if _, err := dec.AddFrame(bad); err != ErrChecksum {
+		t.Fatalf("AddFrame(corrupt) error = %v, want ErrChecksum", err)
+	}
+
+	for _, f := range frames {
+		if done, _ := dec.AddFrame(f.Dots); done {
+			break
+		}
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatal("fountain round-trip with checksum failed")
+	}
+}
@@ -0,0 +1,348 @@
+// Command dotbeam-bench measures how dotbeam configurations hold up over
+// simulated camera channels. For every combination of the swept Config
+// parameters and channel conditions it encodes a random payload, plays the
+// frames as a looping carousel, captures it with a simulated camera (one
+// distorted image per camera frame, see package sim), and decodes the
+// captures until the payload is complete or the loop budget runs out.
+//
+// Each point reports the dot error rate (misread data dots among located
+// captures), the frame error rate (captures the decoder could not use),
+// the time to complete, and the goodput: payload bytes delivered per
+// second of capture, counting failed trials.
+//
+// Usage:
+//
+//	dotbeam-bench
+//	dotbeam-bench -rings 3,4,5 -bits 2,3 -channels clean,phone
+//	dotbeam-bench -parity 0,4,8 -format json -out results.json
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/satindergrewal/dotbeam"
+	"github.com/satindergrewal/dotbeam/sim"
+)
+
+// channels maps the -channels names to capture conditions. Each capture
+// gets its own seed, so random conditions vary from frame to frame.
+var channels = map[string]func(seed uint64) sim.Channel{
+	"clean": func(uint64) sim.Channel { return sim.Channel{} },
+	"mild":  func(seed uint64) sim.Channel { return sim.Random(seed, 0.25) },
+	"phone": func(seed uint64) sim.Channel { return sim.Random(seed, 0.5) },
+	"harsh": func(seed uint64) sim.Channel { return sim.Random(seed, 1) },
+}
+
+// options are the settings shared by every point of a sweep.
+type options struct {
+	payload   int
+	trials    int
+	loops     int
+	size      int
+	cameraFPS float64
+	checksum  bool
+}
+
+// point is one combination of swept parameters.
+type point struct {
+	Rings, Bits, FPS, Parity int
+	Channel                  string
+}
+
+// result is the measurement for one point.
+type result struct {
+	Rings          int     `json:"rings"`
+	BitsPerDot     int     `json:"bits_per_dot"`
+	FPS            int     `json:"fps"`
+	ParityBytes    int     `json:"parity_bytes"`
+	Channel        string  `json:"channel"`
+	Frames         int     `json:"frames"`           // frames per carousel loop
+	Trials         int     `json:"trials"`           // trials run
+	Completed      int     `json:"completed"`        // trials that recovered the payload
+	DotErrorRate   float64 `json:"dot_error_rate"`   // misread data dots / data dots read
+	FrameErrorRate float64 `json:"frame_error_rate"` // unusable captures / captures
+	Seconds        float64 `json:"seconds"`          // mean time to complete; 0 if none did
+	Goodput        float64 `json:"goodput"`          // payload bytes per second of capture
+	DecodeMillis   float64 `json:"decode_ms"`        // wall time per capture in DecodeImage and AddFrame
+	Error          string  `json:"error,omitempty"`  // the configuration could not be encoded
+}
+
+func main() {
+	rings := flag.String("rings", "3,4,5", "Ring counts to sweep")
+	bits := flag.String("bits", "2,3", "Bits per dot to sweep")
+	fps := flag.String("fps", "10", "Carousel frame rates to sweep")
+	parity := flag.String("parity", "0,4", "Reed-Solomon parity bytes per frame to sweep")
+	chans := flag.String("channels", "phone", "Channel conditions to sweep: clean, mild, phone, harsh")
+	payload := flag.Int("bytes", 128, "Payload size in bytes")
+	trials := flag.Int("trials", 2, "Trials per point, each with its own payload and noise")
+	loops := flag.Int("loops", 3, "Carousel loops to watch before giving up")
+	size := flag.Int("size", 480, "Rendered image size in pixels (square)")
+	cameraFPS := flag.Float64("camera-fps", 30, "Camera capture rate")
+	checksum := flag.Bool("checksum", true, "Add the per-frame CRC-16")
+	format := flag.String("format", "csv", "Output format: csv or json")
+	outPath := flag.String("out", "", "Write results here instead of stdout")
+	jobs := flag.Int("j", runtime.NumCPU(), "Points to measure in parallel")
+	quiet := flag.Bool("q", false, "Suppress progress output")
+	flag.Parse()
+
+	points, err := sweep(*rings, *bits, *fps, *parity, *chans)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if *cameraFPS <= 0 {
+		fmt.Fprintln(os.Stderr, "error: -camera-fps must be positive")
+		os.Exit(1)
+	}
+	if *format != "csv" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "error: unknown format %q\n", *format)
+		os.Exit(1)
+	}
+	opts := options{
+		payload:   *payload,
+		trials:    max(1, *trials),
+		loops:     max(1, *loops),
+		size:      *size,
+		cameraFPS: *cameraFPS,
+		checksum:  *checksum,
+	}
+
+	// Measure points in parallel; results keep the sweep order.
+	results := make([]result, len(points))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	finished := 0
+	for range max(1, *jobs) {
+		wg.Go(func() {
+			for i := range work {
+				results[i] = measure(points[i], opts)
+				mu.Lock()
+				finished++
+				logf(*quiet, "  [%d/%d] %s\n", finished, len(points), summary(results[i]))
+				mu.Unlock()
+			}
+		})
+	}
+	for i := range points {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if *format == "json" {
+		err = writeJSON(out, results)
+	} else {
+		err = writeCSV(out, results)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// sweep expands the comma-separated flag values into every combination.
+func sweep(rings, bits, fps, parity, chans string) ([]point, error) {
+	var lists [4][]int
+	for i, s := range []string{rings, bits, fps, parity} {
+		for _, field := range strings.Split(s, ",") {
+			v, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return nil, fmt.Errorf("bad sweep value %q", field)
+			}
+			lists[i] = append(lists[i], v)
+		}
+	}
+	names := strings.Split(chans, ",")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if _, ok := channels[names[i]]; !ok {
+			return nil, fmt.Errorf("unknown channel %q", name)
+		}
+	}
+
+	var points []point
+	for _, r := range lists[0] {
+		for _, b := range lists[1] {
+			for _, f := range lists[2] {
+				if f <= 0 {
+					return nil, fmt.Errorf("bad frame rate %d", f)
+				}
+				for _, p := range lists[3] {
+					for _, name := range names {
+						points = append(points, point{Rings: r, Bits: b, FPS: f, Parity: p, Channel: name})
+					}
+				}
+			}
+		}
+	}
+	return points, nil
+}
+
+// measure runs every trial of one point.
+func measure(p point, o options) result {
+	r := result{
+		Rings:       p.Rings,
+		BitsPerDot:  p.Bits,
+		FPS:         p.FPS,
+		ParityBytes: p.Parity,
+		Channel:     p.Channel,
+		Trials:      o.trials,
+	}
+
+	cfg := dotbeam.DefaultConfig()
+	cfg.Rings = p.Rings
+	cfg.BitsPerDot = p.Bits
+	cfg.FPS = p.FPS
+	cfg.ParityBytes = p.Parity
+	cfg.Checksum = o.checksum
+	layout := dotbeam.NewLayout(cfg, 1, 1)
+	channel := channels[p.Channel]
+
+	var dotErrors, dotsRead, frameErrors, captures int
+	var completeSeconds, totalSeconds float64
+	var decodeTime time.Duration
+	for trial := range o.trials {
+		payload := make([]byte, o.payload)
+		rand.NewChaCha8([32]byte{byte(trial), byte(trial >> 8)}).Read(payload)
+
+		frames, err := dotbeam.NewEncoder(cfg).Encode(payload)
+		if err != nil {
+			r.Error = err.Error()
+			return r
+		}
+		r.Frames = len(frames)
+		rendered := make([]*image.RGBA, len(frames))
+
+		// The camera samples the carousel at its own rate: a fast carousel
+		// gets frames skipped, a slow one gets them captured repeatedly.
+		dec := dotbeam.NewDecoder(cfg)
+		budget := int(math.Ceil(float64(o.loops*len(frames)) * o.cameraFPS / float64(p.FPS)))
+		done := false
+		n := 0
+		for ; n < budget && !done; n++ {
+			shown := int(float64(n)/o.cameraFPS*float64(p.FPS)) % len(frames)
+			if rendered[shown] == nil {
+				rendered[shown] = dotbeam.RenderFrame(frames[shown], layout, o.size, o.size)
+			}
+			img := channel(uint64(trial)<<32 | uint64(n)).Apply(rendered[shown])
+
+			start := time.Now()
+			dots, _, err := dotbeam.DecodeImage(img, cfg)
+			if err == nil {
+				for i, dot := range dots {
+					if !dot.Pilot {
+						dotsRead++
+						if dot.Value != frames[shown].Dots[i].Value {
+							dotErrors++
+						}
+					}
+				}
+				done, err = dec.AddFrame(dots)
+			}
+			decodeTime += time.Since(start)
+			if err != nil {
+				frameErrors++
+			}
+		}
+		captures += n
+
+		elapsed := float64(n) / o.cameraFPS
+		totalSeconds += elapsed
+		if done {
+			if data, err := dec.Data(); err == nil && bytes.HasPrefix(data, payload) {
+				r.Completed++
+				completeSeconds += elapsed
+			}
+		}
+	}
+
+	if dotsRead > 0 {
+		r.DotErrorRate = float64(dotErrors) / float64(dotsRead)
+	}
+	if captures > 0 {
+		r.FrameErrorRate = float64(frameErrors) / float64(captures)
+		r.DecodeMillis = decodeTime.Seconds() * 1000 / float64(captures)
+	}
+	if r.Completed > 0 {
+		r.Seconds = completeSeconds / float64(r.Completed)
+	}
+	if totalSeconds > 0 {
+		r.Goodput = float64(r.Completed*o.payload) / totalSeconds
+	}
+	return r
+}
+
+// summary is the one-line progress report for a result.
+func summary(r result) string {
+	head := fmt.Sprintf("rings %d bits %d fps %d parity %d %s:", r.Rings, r.BitsPerDot, r.FPS, r.ParityBytes, r.Channel)
+	if r.Error != "" {
+		return head + " " + r.Error
+	}
+	return fmt.Sprintf("%s DER %.4f FER %.3f %d/%d complete %.1fs %.1f B/s",
+		head, r.DotErrorRate, r.FrameErrorRate, r.Completed, r.Trials, r.Seconds, r.Goodput)
+}
+
+func writeJSON(w io.Writer, results []result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+func writeCSV(w io.Writer, results []result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"rings", "bits_per_dot", "fps", "parity_bytes", "channel", "frames", "trials", "completed",
+		"dot_error_rate", "frame_error_rate", "seconds", "goodput", "decode_ms", "error",
+	})
+	for _, r := range results {
+		cw.Write([]string{
+			strconv.Itoa(r.Rings),
+			strconv.Itoa(r.BitsPerDot),
+			strconv.Itoa(r.FPS),
+			strconv.Itoa(r.ParityBytes),
+			r.Channel,
+			strconv.Itoa(r.Frames),
+			strconv.Itoa(r.Trials),
+			strconv.Itoa(r.Completed),
+			strconv.FormatFloat(r.DotErrorRate, 'f', 5, 64),
+			strconv.FormatFloat(r.FrameErrorRate, 'f', 4, 64),
+			strconv.FormatFloat(r.Seconds, 'f', 2, 64),
+			strconv.FormatFloat(r.Goodput, 'f', 1, 64),
+			strconv.FormatFloat(r.DecodeMillis, 'f', 2, 64),
+			r.Error,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func logf(quiet bool, format string, args ...any) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
@@ -0,0 +1,293 @@
+// Command dotbeam-decode recovers a payload from captured dotbeam frames:
+// a directory of PNG/JPEG images, an animated GIF, or a glob pattern. Every
+// image is run through the Go image decoder and fed to a Decoder; the
+// recovered bytes are written to a file or stdout.
+//
+// Usage:
+//
+//	dotbeam-decode -out message.txt frames/
+//	dotbeam-decode output.gif
+//	dotbeam-decode 'captures/*.jpg' > payload.bin
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"iter"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/satindergrewal/dotbeam"
+)
+
+// source is one captured image and a label for diagnostics.
+type source struct {
+	name string
+	img  image.Image
+}
+
+func main() {
+	outPath := flag.String("out", "-", "Output file for the recovered payload (- for stdout)")
+	rings := flag.Int("rings", 4, "Number of data rings")
+	fountain := flag.Bool("fountain", false, "Frames are LT fountain coded")
+	checksum := flag.Bool("checksum", false, "Frames carry a CRC-16")
+	parity := flag.Int("parity", 0, "Reed-Solomon parity bytes per frame")
+	preamble := flag.Bool("preamble", false, "Payload starts with a metadata preamble")
+	indexBytes := flag.Int("index-bytes", 0, "Versioned header index/total width (0 = legacy 2-byte header)")
+	session := flag.Bool("session", false, "Frames carry a session ID; lock onto one transfer")
+	bits := flag.Int("bits", 3, "Bits per dot: 1, 2, 3 or 4")
+	cvd := flag.Bool("cvd", false, "Use the color-vision-deficiency safe palette (3 bits per dot)")
+	pilots := flag.Bool("pilots", false, "Reserve pilot dots, one per palette color, for color correction")
+	gray := flag.Bool("gray", false, "Dot values are Gray-coded")
+	passphrase := flag.String("passphrase", "", "Passphrase for encrypted transfers (implies -preamble)")
+	verify := flag.String("verify", "", "Hex Ed25519 public key the transfer must be signed with (implies -preamble)")
+	erasure := flag.Float64("erasure", 0, "Treat dots matched with confidence below this (0.5-1) as RS erasures (needs -parity)")
+	votes := flag.Int("votes", 0, "Reads of each frame to majority-vote before accepting it (0 = first clean read)")
+	totalReads := flag.Int("total-reads", 0, "Frames that must agree on the frame total before it is trusted (0 = trust the first frame)")
+	quiet := flag.Bool("q", false, "Suppress per-frame diagnostics")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: dotbeam-decode [flags] <dir|file.gif|glob>...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := dotbeam.DefaultConfig()
+	cfg.Rings = *rings
+	cfg.UseFountain = *fountain
+	cfg.Checksum = *checksum
+	cfg.ParityBytes = *parity
+	cfg.Preamble = *preamble
+	cfg.IndexBytes = *indexBytes
+	cfg.Session = *session
+	cfg.BitsPerDot = *bits
+	if *cvd {
+		cfg.Palette = dotbeam.PaletteCVD
+	}
+	cfg.GrayCode = *gray
+	cfg.Pilots = *pilots
+	cfg.ErasureThreshold = *erasure
+	cfg.Votes = *votes
+	cfg.TotalReads = *totalReads
+	if *passphrase != "" || *verify != "" {
+		cfg.Preamble = true
+	}
+
+	var paths []string
+	for _, arg := range flag.Args() {
+		p, err := imagePaths(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		paths = append(paths, p...)
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "error: no images found")
+		os.Exit(1)
+	}
+
+	// Images are loaded one at a time and reading stops once the transfer
+	// is complete, so a large capture directory is never held in memory.
+	dec := dotbeam.NewDecoder(cfg)
+	dec.SetPassphrase(*passphrase)
+	done := false
+	read, advanced := 0, 0
+	for src, err := range images(paths) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		read++
+		dots, t, err := dotbeam.DecodeImage(src.img, cfg)
+		if err != nil {
+			logf(*quiet, "  %s: %v\n", src.name, err)
+			continue
+		}
+
+		// A nil error also covers held reads (votes, unlocked totals) and
+		// duplicates; only a change in progress means the frame was used.
+		before := dec.Progress()
+		done, err = dec.AddFrame(dots)
+		if err != nil {
+			logf(*quiet, "  %s: center (%.0f,%.0f) scale %.1f rot %.1f°%s: %v\n",
+				src.name, t.CenterX, t.CenterY, t.Scale, t.Rotation*180/math.Pi, mirrored(t), err)
+			continue
+		}
+		if dec.Progress() != before {
+			advanced++
+		}
+		logf(*quiet, "  %s: center (%.0f,%.0f) scale %.1f rot %.1f°%s corrected %d → %3.0f%%\n",
+			src.name, t.CenterX, t.CenterY, t.Scale, t.Rotation*180/math.Pi, mirrored(t),
+			dec.Corrected(), dec.Progress()*100)
+		if done {
+			break
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%d of %d images advanced the transfer, progress %.0f%%\n",
+		advanced, read, dec.Progress()*100)
+	if !done {
+		fmt.Fprintln(os.Stderr, "error: transfer incomplete")
+		os.Exit(1)
+	}
+
+	data, err := dec.Data()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if id, ok := dec.SessionID(); ok {
+		fmt.Fprintf(os.Stderr, "  session %04x\n", id)
+	}
+	if cfg.Preamble {
+		if meta, err := dec.Metadata(); err == nil {
+			fmt.Fprintf(os.Stderr, "  content type %q, filename %q\n", meta.ContentType, meta.Filename)
+			if meta.Signer != "" && *verify == "" {
+				fmt.Fprintf(os.Stderr, "  signed by %s (not verified, use -verify)\n", meta.Signer)
+			}
+		}
+	}
+	if *verify != "" {
+		pub, err := hex.DecodeString(*verify)
+		if err == nil {
+			err = dec.Verify(ed25519.PublicKey(pub))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: signature check failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "  signature OK (%s)\n", dotbeam.Fingerprint(pub))
+	}
+
+	if *outPath == "-" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*outPath, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing %s: %v\n", *outPath, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "  %d bytes → %s\n", len(data), *outPath)
+}
+
+// imagePaths expands one command-line argument into image files, sorted
+// by name: every image in a directory, or every glob match.
+func imagePaths(arg string) ([]string, error) {
+	var paths []string
+	if info, err := os.Stat(arg); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && isImage(e.Name()) {
+				paths = append(paths, filepath.Join(arg, e.Name()))
+			}
+		}
+	} else {
+		if paths, err = filepath.Glob(arg); err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", arg, err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("%s: no such file", arg)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// images loads the files in paths one at a time, yielding every frame of
+// an animated GIF in turn. It stops at the first file that fails to load.
+func images(paths []string) iter.Seq2[source, error] {
+	return func(yield func(source, error) bool) {
+		for _, p := range paths {
+			if strings.EqualFold(filepath.Ext(p), ".gif") {
+				if !gifFrames(p, yield) {
+					return
+				}
+				continue
+			}
+
+			f, err := os.Open(p)
+			if err != nil {
+				yield(source{}, err)
+				return
+			}
+			img, _, err := image.Decode(f)
+			f.Close()
+			if err != nil {
+				yield(source{}, fmt.Errorf("%s: %w", p, err))
+				return
+			}
+			if !yield(source{name: filepath.Base(p), img: img}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// gifFrames yields every frame of an animated GIF, composited onto a
+// running canvas so partial frames decode like the full picture. The
+// canvas is reused, so each frame is only valid until the next is yielded.
+// It reports whether the caller wants more images.
+func gifFrames(path string, yield func(source, error) bool) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		yield(source{}, err)
+		return false
+	}
+	g, err := gif.DecodeAll(f)
+	f.Close()
+	if err != nil {
+		yield(source{}, fmt.Errorf("%s: %w", path, err))
+		return false
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	for i, frame := range g.Image {
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		g.Image[i] = nil // only the canvas is needed from here on
+		name := fmt.Sprintf("%s[%d]", filepath.Base(path), i)
+		if !yield(source{name: name, img: canvas}, nil) {
+			return false
+		}
+	}
+	return true
+}
+
+func isImage(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+		return true
+	}
+	return false
+}
+
+func logf(quiet bool, format string, args ...any) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// mirrored labels a transform that flipped the image back.
+func mirrored(t dotbeam.Transform) string {
+	if t.Mirrored {
+		return " mirrored"
+	}
+	return ""
+}
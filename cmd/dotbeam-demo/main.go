@@ -68,12 +68,21 @@ type apiResponse struct {
 func main() {
 	data := flag.String("data", "Hello from dotbeam!", "message to encode")
 	port := flag.Int("port", 8443, "HTTPS listen port")
+	bits := flag.Int("bits", 3, "bits per dot: 1, 2, 3 or 4 (the scanner reads 3 only)")
+	cvd := flag.Bool("cvd", false, "use the color-vision-deficiency safe palette (3 bits per dot)")
 	flag.Parse()
 
 	// Encode the data.
 	cfg := dotbeam.DefaultConfig()
+	cfg.BitsPerDot = *bits
+	if *cvd {
+		cfg.Palette = dotbeam.PaletteCVD
+	}
 	enc := dotbeam.NewEncoder(cfg)
-	frames := enc.Encode([]byte(*data))
+	frames, err := enc.Encode([]byte(*data))
+	if err != nil {
+		log.Fatalf("encode: %v", err)
+	}
 
 	// Build a layout so we can pass anchor positions to the client.
 	// Use a 400x400 canvas as the reference size; the renderer can scale.
@@ -148,8 +157,9 @@ func buildResponse(frames []dotbeam.Frame, cfg dotbeam.Config, layout dotbeam.La
 	}
 
 	// Colors.
-	colors := make([]string, len(dotbeam.DefaultColors))
-	for i, c := range dotbeam.DefaultColors {
+	palette := cfg.Colors()
+	colors := make([]string, len(palette))
+	for i, c := range palette {
 		colors[i] = c.Hex()
 	}
 
@@ -1,36 +1,102 @@
 // Command dotbeam-render encodes a message into dotbeam frames and renders
-// them as PNG images. Optionally stitches them into an animated GIF using ffmpeg.
+// them as PNG images. Optionally writes them as an animated GIF as well.
 //
 // Usage:
 //
 //	dotbeam-render -msg "Hello world" -out frames/ -gif output.gif
+//	dotbeam-render -in contact.vcf -index-bytes 2 -out frames/
+//	cat config.json | dotbeam-render -in - -compress -out frames/
+//	dotbeam-render -fountain -checksum -parity 4 -sign <hex seed> -gif out.gif
 package main
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"image/gif"
 	"image/png"
+	"io"
+	"iter"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/satindergrewal/dotbeam"
 )
 
 func main() {
 	msg := flag.String("msg", "Hello, dotbeam!", "Message to encode")
+	inPath := flag.String("in", "", "Encode this file instead of -msg (- for stdin)")
 	outDir := flag.String("out", "frames", "Output directory for PNG frames")
-	gifPath := flag.String("gif", "", "Output GIF path (requires ffmpeg)")
+	gifPath := flag.String("gif", "", "Output animated GIF path")
 	size := flag.Int("size", 800, "Image size in pixels (square)")
+	fountain := flag.Bool("fountain", false, "LT fountain code the frames (renders one finite carousel)")
+	checksum := flag.Bool("checksum", false, "Append a CRC-16 to every frame")
+	parity := flag.Int("parity", 0, "Reed-Solomon parity bytes per frame")
+	indexBytes := flag.Int("index-bytes", 0, "Versioned header index/total width (0 = legacy 2-byte header)")
+	session := flag.Bool("session", false, "Tag frames with a random session ID")
+	bits := flag.Int("bits", 3, "Bits per dot: 1, 2, 3 or 4 (2, 4, 8 or 16 colors)")
+	cvd := flag.Bool("cvd", false, "Use the color-vision-deficiency safe palette (3 bits per dot)")
+	pilots := flag.Bool("pilots", false, "Reserve pilot dots, one per palette color, for color correction")
+	gray := flag.Bool("gray", false, "Gray-code dot values so hue neighbours differ by one bit")
+	preamble := flag.Bool("preamble", false, "Prepend the metadata preamble (exact length)")
+	passphrase := flag.String("passphrase", "", "Encrypt with a passphrase (implies -preamble)")
+	compress := flag.Bool("compress", false, "DEFLATE-compress the payload when it helps (implies -preamble)")
+	sign := flag.String("sign", "", "Sign with this hex Ed25519 seed or private key (implies -preamble)")
 	flag.Parse()
 
+	var signKey ed25519.PrivateKey
+	if *sign != "" {
+		key, err := hex.DecodeString(*sign)
+		switch {
+		case err != nil:
+		case len(key) == ed25519.SeedSize:
+			signKey = ed25519.NewKeyFromSeed(key)
+		case len(key) == ed25519.PrivateKeySize:
+			signKey = ed25519.PrivateKey(key)
+		}
+		if signKey == nil {
+			fmt.Fprintln(os.Stderr, "error: -sign needs a 32-byte seed or 64-byte private key in hex")
+			os.Exit(1)
+		}
+	}
+
 	cfg := dotbeam.DefaultConfig()
+	cfg.UseFountain = *fountain
+	cfg.Checksum = *checksum
+	cfg.ParityBytes = *parity
+	cfg.IndexBytes = *indexBytes
+	cfg.Session = *session
+	cfg.BitsPerDot = *bits
+	if *cvd {
+		cfg.Palette = dotbeam.PaletteCVD
+	}
+	cfg.GrayCode = *gray
+	cfg.Pilots = *pilots
+	cfg.Compress = *compress
+	cfg.Preamble = *preamble || *passphrase != "" || *compress || signKey != nil
 	enc := dotbeam.NewEncoder(cfg)
-	frames := enc.Encode([]byte(*msg))
+	enc.SetPassphrase(*passphrase)
+	if signKey != nil {
+		enc.Sign(signKey)
+		pub := signKey.Public().(ed25519.PublicKey)
+		fmt.Printf("  signing as %s (verify with -verify %x)\n", dotbeam.Fingerprint(pub), []byte(pub))
+	}
 
-	if len(frames) == 0 {
-		fmt.Fprintln(os.Stderr, "error: message produced no frames")
-		os.Exit(1)
+	var in io.Reader = strings.NewReader(*msg)
+	switch *inPath {
+	case "":
+	case "-":
+		in = os.Stdin
+	default:
+		f, err := os.Open(*inPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
 	}
 
 	// Create output directory
@@ -41,10 +107,27 @@ func main() {
 
 	layout := dotbeam.NewLayout(cfg, 1, 1) // normalized coordinates
 
-	fmt.Printf("Encoding %d bytes into %d frames (%d dots/frame, %d bits/dot)\n",
-		len(*msg), len(frames), cfg.TotalDots(), cfg.BitsPerDot)
+	// Frames are rendered as they are encoded; they are only kept in
+	// memory when a GIF is requested.
+	var frames []dotbeam.Frame
+	count := 0
+	stream := enc.EncodeStream(in)
+	if cfg.UseFountain {
+		stream = carousel(enc, in)
+	}
+	for frame, err := range stream {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if count == 0 && cfg.Session {
+			fmt.Printf("  session %04x\n", enc.SessionID())
+		}
+		count++
+		if *gifPath != "" {
+			frames = append(frames, frame)
+		}
 
-	for _, frame := range frames {
 		img := dotbeam.RenderFrame(frame, layout, *size, *size)
 
 		filename := filepath.Join(*outDir, fmt.Sprintf("frame_%03d.png", frame.Index))
@@ -59,50 +142,59 @@ func main() {
 			os.Exit(1)
 		}
 		f.Close()
-		fmt.Printf("  frame %d/%d → %s\n", frame.Index+1, len(frames), filename)
+		if cfg.UseFountain {
+			fmt.Printf("  symbol %d (K=%d) → %s\n", frame.Index, frame.Total, filename)
+		} else {
+			fmt.Printf("  frame %d/%d → %s\n", frame.Index+1, frame.Total, filename)
+		}
 	}
+	if count == 0 {
+		fmt.Fprintln(os.Stderr, "error: message produced no frames")
+		os.Exit(1)
+	}
+	fmt.Printf("Encoded %d frames (%d dots/frame, %d bits/dot)\n",
+		count, cfg.TotalDots(), cfg.BitsPerDot)
 
-	// Generate GIF with ffmpeg if requested
+	// Write animated GIF if requested
 	if *gifPath != "" {
-		if _, err := exec.LookPath("ffmpeg"); err != nil {
-			fmt.Fprintln(os.Stderr, "warning: ffmpeg not found, skipping GIF generation")
-		} else {
-			inputPattern := filepath.Join(*outDir, "frame_%03d.png")
-			fps := fmt.Sprintf("%d", cfg.FPS)
+		g, err := dotbeam.RenderGIF(frames, layout, *size, *size)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error rendering GIF: %v\n", err)
+			os.Exit(1)
+		}
+		f, err := os.Create(*gifPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating %s: %v\n", *gifPath, err)
+			os.Exit(1)
+		}
+		if err := gif.EncodeAll(f, g); err != nil {
+			f.Close()
+			fmt.Fprintf(os.Stderr, "error encoding GIF: %v\n", err)
+			os.Exit(1)
+		}
+		f.Close()
+		fmt.Printf("  GIF → %s (%d FPS, loop forever)\n", *gifPath, cfg.FPS)
+	}
 
-			// Two-pass for better GIF quality: generate palette first, then apply
-			palettePath := filepath.Join(*outDir, "palette.png")
-			cmd1 := exec.Command("ffmpeg", "-y",
-				"-framerate", fps,
-				"-i", inputPattern,
-				"-vf", "palettegen=max_colors=64",
-				palettePath,
-			)
-			cmd1.Stderr = os.Stderr
-			if err := cmd1.Run(); err != nil {
-				fmt.Fprintf(os.Stderr, "ffmpeg palette error: %v\n", err)
-				os.Exit(1)
-			}
+	fmt.Println("Done.")
+}
 
-			cmd2 := exec.Command("ffmpeg", "-y",
-				"-framerate", fps,
-				"-i", inputPattern,
-				"-i", palettePath,
-				"-lavfi", "paletteuse=dither=none",
-				"-loop", "0",
-				*gifPath,
-			)
-			cmd2.Stderr = os.Stderr
-			if err := cmd2.Run(); err != nil {
-				fmt.Fprintf(os.Stderr, "ffmpeg GIF error: %v\n", err)
-				os.Exit(1)
+// carousel returns the finite fountain carousel Encode builds for the
+// bytes read from r. EncodeStream would yield LT symbols without end.
+func carousel(enc *dotbeam.Encoder, r io.Reader) iter.Seq2[dotbeam.Frame, error] {
+	return func(yield func(dotbeam.Frame, error) bool) {
+		data, err := io.ReadAll(r)
+		if err == nil {
+			var frames []dotbeam.Frame
+			if frames, err = enc.Encode(data); err == nil {
+				for _, frame := range frames {
+					if !yield(frame, nil) {
+						return
+					}
+				}
+				return
 			}
-
-			// Clean up palette
-			os.Remove(palettePath)
-			fmt.Printf("  GIF → %s (%d FPS, loop forever)\n", *gifPath, cfg.FPS)
 		}
+		yield(dotbeam.Frame{}, err)
 	}
-
-	fmt.Println("Done.")
 }
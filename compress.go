@@ -0,0 +1,36 @@
+package dotbeam
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// maxInflatedSize bounds decompressed payloads so a corrupt or hostile
+// stream cannot exhaust memory.
+const maxInflatedSize = 64 << 20
+
+// compress returns data as raw DEFLATE and sets the compressed flag on p,
+// or returns data unchanged if compression would not shorten it.
+func compress(p *preamble, data []byte) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	w.Write(data)
+	w.Close()
+	if buf.Len() >= len(data) {
+		return data
+	}
+	p.flags |= flagCompressed
+	return buf.Bytes()
+}
+
+// decompress inflates a raw DEFLATE payload.
+func decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxInflatedSize+1))
+	if err != nil || len(out) > maxInflatedSize {
+		return nil, ErrDecompress
+	}
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package dotbeam
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat(`{"ssid":"office","band":"5GHz","hidden":false},`, 12))
+
+	config := DefaultConfig()
+	config.Preamble = true
+	plain := mustEncode(t, NewEncoder(config), data)
+	config.Compress = true
+	frames := mustEncode(t, NewEncoder(config), data)
+	if len(frames)*2 > len(plain) {
+		t.Errorf("compressed transfer has %d frames, uncompressed %d", len(frames), len(plain))
+	}
+
+	if got := roundTrip(t, config, data); !bytes.Equal(got, data) {
+		t.Fatal("round-trip failed")
+	}
+}
+
+func TestCompressSkipsIncompressible(t *testing.T) {
+	data := make([]byte, 200)
+	rand.New(rand.NewSource(12)).Read(data)
+
+	var p preamble
+	if out := compress(&p, data); !bytes.Equal(out, data) || p.flags&flagCompressed != 0 {
+		t.Error("random data was compressed")
+	}
+
+	config := DefaultConfig()
+	config.Preamble = true
+	config.Compress = true
+	if got := roundTrip(t, config, data); !bytes.Equal(got, data) {
+		t.Fatal("round-trip failed")
+	}
+}
+
+func TestCompressEncryptedSigned(t *testing.T) {
+	pub, priv := testKey(4)
+	config := DefaultConfig()
+	config.Preamble = true
+	config.Compress = true
+	enc := NewEncoder(config)
+	enc.SetPassphrase("pw")
+	enc.Sign(priv)
+	data := []byte(strings.Repeat("BEGIN:VCARD\nFN:Alice\nEND:VCARD\n", 6))
+
+	dec := NewDecoder(config)
+	dec.SetPassphrase("pw")
+	got, err := decodeAll(dec, mustEncode(t, enc, data))
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("round-trip failed")
+	}
+	if err := dec.Verify(pub); err != nil {
+		t.Errorf("Verify = %v, want nil", err)
+	}
+	meta, err := dec.Metadata()
+	if err != nil || meta.OriginalLength != len(data) || meta.Length >= len(data) {
+		t.Errorf("Metadata() = %+v, %v; want OriginalLength %d and a shorter Length", meta, err, len(data))
+	}
+}
+
+func TestCompressRequiresPreamble(t *testing.T) {
+	config := DefaultConfig()
+	config.Compress = true
+	if _, err := NewEncoder(config).Encode([]byte("x")); err != ErrInvalidConfig {
+		t.Errorf("Encode without preamble = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestDecompressCorrupt(t *testing.T) {
+	if _, err := decompress([]byte{0xff, 0xff, 0xff}); err != ErrDecompress {
+		t.Errorf("decompress(garbage) = %v, want ErrDecompress", err)
+	}
+}
@@ -0,0 +1,175 @@
+package dotbeam
+
+import "math"
+
+// Color-vision-deficiency (CVD) checks.
+//
+// MinDistance reports how far apart the closest two palette colors are, in
+// CIEDE2000 ΔE, as seen with normal vision or simulated dichromacy. The
+// simulation applies the Machado, Oliveira and Fernandes (2009) matrices
+// at full severity in linear RGB. A ΔE below about 10 is hard to tell apart
+// at a glance; below about 2 most people see no difference at all.
+
+// Vision selects the color vision MinDistance simulates.
+type Vision int
+
+const (
+	NormalVision Vision = iota
+	Protanopia          // no long-wavelength (red) cones
+	Deuteranopia        // no medium-wavelength (green) cones
+	Tritanopia          // no short-wavelength (blue) cones
+)
+
+// cvdMatrices holds the linear-RGB simulation matrix for each deficiency.
+var cvdMatrices = map[Vision][3][3]float64{
+	Protanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	Deuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	Tritanopia: {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// MinDistance returns the smallest CIEDE2000 distance between any two
+// colors of the palette as seen with vision v.
+func (p Palette) MinDistance(v Vision) float64 {
+	labs := make([]lab, len(p))
+	for i, c := range p {
+		labs[i] = toLab(simulate(linearRGB(c), v))
+	}
+	best := math.Inf(1)
+	for i := range labs {
+		for j := i + 1; j < len(labs); j++ {
+			best = math.Min(best, ciede2000(labs[i], labs[j]))
+		}
+	}
+	return best
+}
+
+// MinCVDDistance returns the smallest MinDistance under simulated
+// protanopia, deuteranopia and tritanopia.
+func (p Palette) MinCVDDistance() float64 {
+	return min(p.MinDistance(Protanopia), p.MinDistance(Deuteranopia), p.MinDistance(Tritanopia))
+}
+
+// linearRGB converts an sRGB color to linear RGB in [0, 1].
+func linearRGB(c Color) [3]float64 {
+	lin := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return [3]float64{lin(c.R), lin(c.G), lin(c.B)}
+}
+
+// simulate applies the CVD matrix for v to a linear RGB color.
+func simulate(rgb [3]float64, v Vision) [3]float64 {
+	m, ok := cvdMatrices[v]
+	if !ok {
+		return rgb
+	}
+	var out [3]float64
+	for i := range out {
+		out[i] = min(1, max(0, m[i][0]*rgb[0]+m[i][1]*rgb[1]+m[i][2]*rgb[2]))
+	}
+	return out
+}
+
+// lab is a CIE L*a*b* color (D65 white point).
+type lab struct {
+	L, A, B float64
+}
+
+// toLab converts linear RGB (sRGB primaries) to CIE L*a*b*.
+func toLab(rgb [3]float64) lab {
+	r, g, b := rgb[0], rgb[1], rgb[2]
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// ciede2000 returns the CIEDE2000 color difference between two colors,
+// with unit weighting factors (Sharma, Wu and Dalal, 2005).
+func ciede2000(c1, c2 lab) float64 {
+	const deg = math.Pi / 180
+
+	cAvg := (math.Hypot(c1.A, c1.B) + math.Hypot(c2.A, c2.B)) / 2
+	c7 := math.Pow(cAvg, 7)
+	g := 0.5 * (1 - math.Sqrt(c7/(c7+math.Pow(25, 7))))
+
+	a1, a2 := (1+g)*c1.A, (1+g)*c2.A
+	cp1, cp2 := math.Hypot(a1, c1.B), math.Hypot(a2, c2.B)
+	hue := func(a, b float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / deg
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	hp1, hp2 := hue(a1, c1.B), hue(a2, c2.B)
+
+	dL := c2.L - c1.L
+	dC := cp2 - cp1
+	var dh float64
+	if cp1*cp2 != 0 {
+		dh = hp2 - hp1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(cp1*cp2) * math.Sin(dh/2*deg)
+
+	lAvg := (c1.L + c2.L) / 2
+	cpAvg := (cp1 + cp2) / 2
+	hAvg := hp1 + hp2
+	if cp1*cp2 != 0 {
+		switch {
+		case math.Abs(hp1-hp2) <= 180:
+			hAvg /= 2
+		case hp1+hp2 < 360:
+			hAvg = (hAvg + 360) / 2
+		default:
+			hAvg = (hAvg - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos((hAvg-30)*deg) + 0.24*math.Cos(2*hAvg*deg) +
+		0.32*math.Cos((3*hAvg+6)*deg) - 0.20*math.Cos((4*hAvg-63)*deg)
+	l50 := (lAvg - 50) * (lAvg - 50)
+	sL := 1 + 0.015*l50/math.Sqrt(20+l50)
+	sC := 1 + 0.045*cpAvg
+	sH := 1 + 0.015*cpAvg*t
+
+	cp7 := math.Pow(cpAvg, 7)
+	rC := 2 * math.Sqrt(cp7/(cp7+math.Pow(25, 7)))
+	dTheta := 30 * math.Exp(-((hAvg-275)/25)*((hAvg-275)/25))
+	rT := -math.Sin(2*dTheta*deg) * rC
+
+	kl, kc, kh := dL/sL, dC/sC, dH/sH
+	return math.Sqrt(kl*kl + kc*kc + kh*kh + rT*kc*kh)
+}
@@ -0,0 +1,62 @@
+package dotbeam
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestCIEDE2000(t *testing.T) {
+	// Test pairs from Sharma, Wu and Dalal (2005).
+	tests := []struct {
+		a, b lab
+		want float64
+	}{
+		{lab{50, 2.6772, -79.7751}, lab{50, 0, -82.7485}, 2.0425},
+		{lab{50, 0, 0}, lab{50, -1, 2}, 2.3669},
+		{lab{50, 2.5, 0}, lab{73, 25, -18}, 27.1492},
+		{lab{60.2574, -34.0099, 36.2677}, lab{60.4626, -34.1751, 39.4387}, 1.2644},
+		{lab{2.0776, 0.0795, -1.135}, lab{0.9033, -0.0636, -0.5514}, 0.9082},
+	}
+	for _, tt := range tests {
+		if got := ciede2000(tt.a, tt.b); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("ciede2000(%v, %v) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPaletteCVD(t *testing.T) {
+	withBg := append(Palette{{R: bgColor.R, G: bgColor.G, B: bgColor.B}}, PaletteCVD...)
+	for _, v := range []Vision{NormalVision, Protanopia, Deuteranopia, Tritanopia} {
+		if d := withBg.MinDistance(v); d < 19 {
+			t.Errorf("PaletteCVD MinDistance(%d) = %.2f, want >= 19", v, d)
+		}
+	}
+	if d, def := PaletteCVD.MinCVDDistance(), Palette8.MinCVDDistance(); d <= def {
+		t.Errorf("PaletteCVD MinCVDDistance = %.2f, no better than DefaultColors (%.2f)", d, def)
+	}
+}
+
+func TestPaletteCVDRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	cfg.Palette = PaletteCVD
+	msg := []byte("readable by everyone")
+
+	got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image { return img })
+	if !bytes.HasPrefix(got, msg) {
+		t.Fatalf("round-trip mismatch:\n got: %q\nwant prefix: %q", got, msg)
+	}
+}
+
+func TestSimulateNormalVision(t *testing.T) {
+	// White stays white under every simulation; the matrices' rows sum to 1.
+	white := linearRGB(Color{R: 0xFF, G: 0xFF, B: 0xFF})
+	for _, v := range []Vision{NormalVision, Protanopia, Deuteranopia, Tritanopia} {
+		got := toLab(simulate(white, v))
+		if math.Abs(got.L-100) > 0.01 || math.Abs(got.A) > 0.01 || math.Abs(got.B) > 0.01 {
+			t.Errorf("white under vision %d = %+v, want L*a*b* (100, 0, 0)", v, got)
+		}
+	}
+}
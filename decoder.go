@@ -1,65 +1,378 @@
 package dotbeam
 
-import "errors"
+import (
+	"cmp"
+	"errors"
+	"slices"
+)
 
 var (
-	ErrIncompleteData = errors.New("dotbeam: incomplete data, not all frames received")
-	ErrInvalidFrame   = errors.New("dotbeam: invalid frame header")
+	ErrIncompleteData  = errors.New("dotbeam: incomplete data, not all frames received")
+	ErrInvalidFrame    = errors.New("dotbeam: invalid frame header")
+	ErrChecksum        = errors.New("dotbeam: frame checksum mismatch")
+	ErrInvalidMetadata = errors.New("dotbeam: invalid metadata preamble")
+	ErrUncorrectable   = errors.New("dotbeam: too many symbol errors to correct")
+	ErrNoAnchors       = errors.New("dotbeam: no anchor triangle found")
+	ErrTooLarge        = errors.New("dotbeam: data needs more frames than the header can address")
+	ErrInvalidConfig   = errors.New("dotbeam: config leaves no room for payload")
+	ErrSessionMismatch = errors.New("dotbeam: frame belongs to a different session")
+	ErrInvalidKey      = errors.New("dotbeam: key must be 16, 24 or 32 bytes")
+	ErrKeyRequired     = errors.New("dotbeam: transfer is encrypted but no key is set")
+	ErrNotEncrypted    = errors.New("dotbeam: key is set but transfer is not encrypted")
+	ErrWrongKey        = errors.New("dotbeam: wrong key or tampered payload")
+	ErrUnsigned        = errors.New("dotbeam: transfer is not signed")
+	ErrBadSignature    = errors.New("dotbeam: signature does not match the public key")
+	ErrDecompress      = errors.New("dotbeam: compressed payload is corrupt or too large")
+	ErrStreamed        = errors.New("dotbeam: data was written to the stream writer")
+	ErrTotalConflict   = errors.New("dotbeam: frames persistently disagree with the locked frame total")
+	ErrGIFPalette      = errors.New("dotbeam: palette has too many colors for a GIF")
 )
 
 // Decoder reassembles data from captured dotbeam frames.
 type Decoder struct {
 	config   Config
+	err      error          // config error, returned by every AddFrame
 	frames   map[int][]byte // frame index → payload
 	total    int
 	received int
+	fountain *ltDecoder // non-nil once a fountain frame has been seen
+
+	corrected int // RS symbol errors corrected in the last frame
+
+	session       uint16 // session the decoder is locked onto
+	sessionLocked bool
+	sessionReads  map[uint16]int // session → frames claiming it, before the lock
+	unlocked      []heldFrame    // frames waiting for the session to lock
+
+	totalReads  map[int]int // frame total → header reads claiming it
+	totalLocked bool
+	held        []heldFrame // frames waiting for the total to lock
+
+	reads      map[int][][]uint8 // frame index → pending dot reads (Votes)
+	confidence map[int]float64   // frame index → confidence of the committed vote
+
+	secret secret // decryption key, if any
+
+	stream *streamWriter // non-nil for decoders from NewStreamDecoder
 }
 
-// NewDecoder creates a new decoder with the given config.
+// NewDecoder creates a new decoder with the given config. If the config is
+// invalid, every AddFrame call returns ErrInvalidConfig.
 func NewDecoder(config Config) *Decoder {
 	return &Decoder{
 		config: config,
+		err:    config.validate(),
 		frames: make(map[int][]byte),
 	}
 }
 
 // AddFrame processes a decoded frame's dot values and stores the payload.
-// Returns true if all frames have been received.
+// Returns true if all frames have been received. With Config.Votes set, a
+// read is held back (false, nil) until the vote for its frame commits.
 func (d *Decoder) AddFrame(dots []Dot) (bool, error) {
+	d.corrected = 0
+	if d.err != nil {
+		return false, d.err
+	}
+	if d.config.Pilots {
+		dots = dataDots(dots)
+	}
 	if len(dots) == 0 {
 		return false, ErrInvalidFrame
 	}
 
-	// Convert dot values back to bytes
-	data := d.dotsToBytes(dots)
-	if len(data) < 2 {
+	var data []byte
+	var err error
+	if d.config.Votes > 1 {
+		data, err = d.vote(dots)
+	} else {
+		data, err = d.readFrame(dots)
+	}
+	if err != nil || data == nil {
+		return false, err
+	}
+	if d.config.UseFountain {
+		return d.addFountainFrame(data)
+	}
+	h, payload, err := d.config.parseHeader(data)
+	if err != nil {
+		return false, err
+	}
+	if h.total == 0 || h.index >= h.total {
 		return false, ErrInvalidFrame
 	}
+	return d.checkSession(h, payload)
+}
+
+// store keeps a regular frame's payload, or feeds a fountain symbol to the
+// peeling decoder, once its total has been accepted.
+func (d *Decoder) store(h frameHeader, body []byte) (bool, error) {
+	if d.config.UseFountain {
+		return d.storeSymbol(h, body)
+	}
 
-	frameIndex := int(data[0])
-	frameTotal := int(data[1])
+	if _, exists := d.frames[h.index]; !exists && !d.flushed(h.index) {
+		d.frames[h.index] = body
+		d.received++
+	}
+	if d.stream != nil {
+		if err := d.flush(); err != nil {
+			return false, err
+		}
+	}
+
+	return d.received >= d.total, nil
+}
+
+// readFrame converts dot values back to frame bytes, applying Reed-Solomon
+// correction and checking the checksum as configured.
+func (d *Decoder) readFrame(dots []Dot) ([]byte, error) {
+	raw := d.dotsToBytes(dots)
+	erasures := d.erasures(dots)
+	data, err := d.correct(raw, erasures)
+	if err != nil && erasures != nil {
+		// Erasures can use up the parity a confident misread elsewhere
+		// needs, or hide it; fall back to plain error correction.
+		data, err = d.correct(raw, nil)
+	}
+	return data, err
+}
+
+// correct applies Reed-Solomon correction with the given erasures and
+// verifies the checksum, as configured.
+func (d *Decoder) correct(data []byte, erasures []int) ([]byte, error) {
+	if d.config.ParityBytes > 0 {
+		var err error
+		data, d.corrected, err = rsDecodeFrame(data, d.config.BitsPerFrame()/8, d.config.ParityBytes, erasures)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if d.config.Checksum {
+		var err error
+		if data, err = verifyChecksum(data, d.config.frameBodySize()); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
 
-	if frameTotal == 0 {
+// addFountainFrame validates a fountain frame and passes its LT symbol on.
+func (d *Decoder) addFountainFrame(data []byte) (bool, error) {
+	blockSize := d.config.BytesPerFrame()
+	h, symbol, err := d.config.parseHeader(data)
+	if err != nil {
+		return false, err
+	}
+	if blockSize <= 0 || len(symbol) < blockSize || h.total == 0 {
 		return false, ErrInvalidFrame
 	}
+	return d.checkSession(h, symbol[:blockSize])
+}
 
-	d.total = frameTotal
-	payload := data[2:]
+// storeSymbol feeds an LT symbol into the peeling decoder.
+func (d *Decoder) storeSymbol(h frameHeader, symbol []byte) (bool, error) {
+	seed, k := h.index, h.total
 
-	if _, exists := d.frames[frameIndex]; !exists {
-		d.frames[frameIndex] = payload
-		d.received++
+	// settle only checks k once the total is locked, which without
+	// Config.TotalReads it never is when Config.Checksum is set. A symbol
+	// from a transfer with another K would corrupt the blocks recovered so
+	// far, so it is rejected here whatever the mode.
+	if d.fountain == nil {
+		d.fountain = newLTDecoder(k, len(symbol))
+		d.total = k
+	} else if k != d.fountain.k {
+		return false, ErrInvalidFrame
 	}
+	d.fountain.add(seed, symbol)
 
-	return d.received >= d.total, nil
+	d.received = d.fountain.recovered
+	if d.stream != nil {
+		if err := d.flush(); err != nil {
+			return false, err
+		}
+	}
+	return d.fountain.complete(), nil
+}
+
+// sessionReads is the number of frames that must agree on a session ID
+// before a decoder without Config.Checksum locks onto it.
+const sessionReads = 2
+
+// checkSession locks the decoder onto one session, rejects frames from any
+// other and settles the rest. It is a no-op unless Config.Session is set.
+// With Config.Checksum a frame's session is trusted at once. Without it a
+// misread first frame could lock a bogus session for good, so frames are
+// held back until sessionReads of them agree on one; the decoder then locks
+// onto it and settles the held frames that carry it.
+func (d *Decoder) checkSession(h frameHeader, body []byte) (bool, error) {
+	if !d.config.Session {
+		return d.settle(h, body)
+	}
+	if d.sessionLocked {
+		if h.session != d.session {
+			return false, ErrSessionMismatch
+		}
+		return d.settle(h, body)
+	}
+	if d.config.Checksum {
+		d.session, d.sessionLocked = h.session, true
+		return d.settle(h, body)
+	}
+
+	if d.sessionReads == nil {
+		d.sessionReads = make(map[uint16]int)
+	}
+	d.sessionReads[h.session]++
+	d.unlocked = append(d.unlocked, heldFrame{h, body})
+	if d.sessionReads[h.session] < sessionReads {
+		return false, nil
+	}
+
+	d.session, d.sessionLocked = h.session, true
+	held := d.unlocked
+	d.unlocked = nil
+	d.sessionReads = nil
+	done := false
+	for _, f := range held {
+		if f.header.session != d.session {
+			continue
+		}
+		var err error
+		if done, err = d.settle(f.header, f.body); err != nil {
+			return false, err
+		}
+	}
+	return done, nil
+}
+
+// heldFrame is a frame received before the session or frame total was
+// locked.
+type heldFrame struct {
+	header frameHeader
+	body   []byte
+}
+
+// fountainReads is the number of fountain frames that must agree on K
+// before a decoder without Config.Checksum builds its LT decoder, which
+// costs O(K) memory: one misread header could otherwise claim a K of
+// millions, or lock the decoder onto a transfer that does not exist.
+const fountainReads = 2
+
+// readsToLock returns the number of frames that must agree on the frame
+// total (or K) before the decoder locks onto it.
+func (d *Decoder) readsToLock() int {
+	if d.config.UseFountain && !d.config.Checksum {
+		return max(d.config.TotalReads, fountainReads)
+	}
+	return max(d.config.TotalReads, 1)
+}
+
+// settle stores a frame once its total is trusted. Frames are held back
+// until one total has been read readsToLock times (once by default); the
+// decoder then locks onto it, stores the held frames that agree and
+// rejects any later frame that does not, so a misread header can neither
+// complete the transfer early nor push Progress past 1. Once frames
+// claiming another total outnumber those claiming the locked one, it
+// returns ErrTotalConflict.
+func (d *Decoder) settle(h frameHeader, body []byte) (bool, error) {
+	needed := d.readsToLock()
+	if d.totalReads == nil {
+		d.totalReads = make(map[int]int)
+	}
+	d.totalReads[h.total]++
+
+	if d.totalLocked {
+		if h.total == d.total {
+			return d.store(h, body)
+		}
+		if d.totalReads[h.total] > d.totalReads[d.total] {
+			return false, ErrTotalConflict
+		}
+		return false, ErrInvalidFrame
+	}
+
+	d.held = append(d.held, heldFrame{h, body})
+	if d.totalReads[h.total] < needed {
+		return false, nil
+	}
+
+	d.totalLocked = true
+	d.total = h.total
+	held := d.held
+	d.held = nil
+	done := false
+	for _, f := range held {
+		if f.header.total != d.total {
+			continue
+		}
+		var err error
+		if done, err = d.store(f.header, f.body); err != nil {
+			return false, err
+		}
+	}
+	return done, nil
+}
+
+// SessionID returns the session the decoder is locked onto. ok is false
+// until the session is trusted (see Config.Session) or if Config.Session
+// is not set.
+func (d *Decoder) SessionID() (id uint16, ok bool) {
+	return d.session, d.sessionLocked
+}
+
+// Corrected returns the number of byte (symbol) errors Reed-Solomon
+// repaired in the most recent AddFrame call. Always 0 unless
+// Config.ParityBytes is set.
+func (d *Decoder) Corrected() int {
+	return d.corrected
 }
 
 // Data returns the reassembled data. Returns error if incomplete.
+// With Config.Preamble set, the preamble is stripped and exactly the
+// original bytes are returned, decrypted and decompressed as needed.
 func (d *Decoder) Data() ([]byte, error) {
+	if d.stream != nil {
+		return nil, ErrStreamed
+	}
+	result, err := d.assemble()
+	if err != nil || !d.config.Preamble {
+		return result, err
+	}
+	p, n, err := parseTransfer(result)
+	if err != nil {
+		return nil, err
+	}
+	return d.unwrap(p, result[:n], result[n:n+p.Length])
+}
+
+// unwrap decrypts and decompresses a payload as its preamble requires.
+// header is the raw preamble.
+func (d *Decoder) unwrap(p preamble, header, body []byte) ([]byte, error) {
+	if err := d.checkEncrypted(p); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.flags&flagEncrypted != 0 {
+		if body, err = d.open(p, header, body); err != nil {
+			return nil, err
+		}
+	}
+	if p.flags&flagCompressed != 0 {
+		return decompress(body)
+	}
+	return body, nil
+}
+
+// assemble returns every received byte in order, once the transfer is
+// complete.
+func (d *Decoder) assemble() ([]byte, error) {
 	if d.received < d.total {
 		return nil, ErrIncompleteData
 	}
-
+	if d.fountain != nil {
+		return d.fountain.data(), nil
+	}
 	var result []byte
 	for i := 0; i < d.total; i++ {
 		payload, ok := d.frames[i]
@@ -68,10 +381,63 @@ func (d *Decoder) Data() ([]byte, error) {
 		}
 		result = append(result, payload...)
 	}
-
 	return result, nil
 }
 
+// parseTransfer parses the preamble of a complete transfer and checks that
+// the payload and any signature trailer fit in it.
+func parseTransfer(result []byte) (preamble, int, error) {
+	p, n, err := parsePreamble(result)
+	if err == ErrIncompleteData {
+		err = ErrInvalidMetadata
+	}
+	if err != nil {
+		return p, 0, err
+	}
+	if n+p.Length+p.trailerSize() > len(result) {
+		return p, 0, ErrInvalidMetadata
+	}
+	return p, n, nil
+}
+
+// Metadata returns the transfer metadata from the in-band preamble. It is
+// available as soon as the frames carrying the preamble have arrived,
+// usually just the first one. Requires Config.Preamble.
+func (d *Decoder) Metadata() (Metadata, error) {
+	if !d.config.Preamble {
+		return Metadata{}, ErrInvalidMetadata
+	}
+	if d.stream != nil {
+		if d.stream.pre == nil {
+			return Metadata{}, ErrIncompleteData
+		}
+		return d.stream.pre.Metadata, nil
+	}
+	p, _, err := parsePreamble(d.prefix())
+	return p.Metadata, err
+}
+
+// prefix returns the contiguous run of received bytes starting at frame 0.
+func (d *Decoder) prefix() []byte {
+	var result []byte
+	if d.fountain != nil {
+		for _, b := range d.fountain.blocks {
+			if b == nil {
+				break
+			}
+			result = append(result, b...)
+		}
+		return result
+	}
+	for i := 0; ; i++ {
+		payload, ok := d.frames[i]
+		if !ok {
+			return result
+		}
+		result = append(result, payload...)
+	}
+}
+
 // Progress returns the fraction of frames received (0.0 to 1.0).
 func (d *Decoder) Progress() float64 {
 	if d.total == 0 {
@@ -80,11 +446,66 @@ func (d *Decoder) Progress() float64 {
 	return float64(d.received) / float64(d.total)
 }
 
-// Reset clears all received frames.
+// Reset clears all received frames and pending votes and releases the
+// total and session locks so the decoder can follow a new transfer.
 func (d *Decoder) Reset() {
 	d.frames = make(map[int][]byte)
 	d.total = 0
 	d.received = 0
+	d.fountain = nil
+	d.session = 0
+	d.sessionLocked = false
+	d.sessionReads = nil
+	d.unlocked = nil
+	d.totalReads = nil
+	d.totalLocked = false
+	d.held = nil
+	d.reads = nil
+	d.confidence = nil
+	if d.stream != nil {
+		d.stream = &streamWriter{w: d.stream.w}
+	}
+}
+
+// erasures returns the frame byte positions touched by the least confident
+// dots below Config.ErasureThreshold, at most ParityBytes of them, for
+// Reed-Solomon to treat as erasures. Dots with unknown (0) confidence are
+// never erased.
+func (d *Decoder) erasures(dots []Dot) []int {
+	if d.config.ErasureThreshold == 0 || d.config.ParityBytes == 0 {
+		return nil
+	}
+	var weak []int
+	for i, dot := range dots {
+		if dot.Confidence > 0 && dot.Confidence < d.config.ErasureThreshold {
+			weak = append(weak, i)
+		}
+	}
+	slices.SortStableFunc(weak, func(a, b int) int {
+		return cmp.Compare(dots[a].Confidence, dots[b].Confidence)
+	})
+
+	n := d.config.BitsPerFrame() / 8
+	bitsPerDot := d.config.BitsPerDot
+	var positions []int
+	seen := make(map[int]bool)
+	for _, i := range weak {
+		first, last := i*bitsPerDot/8, ((i+1)*bitsPerDot-1)/8
+		var add []int
+		for p := first; p <= last && p < n; p++ {
+			if !seen[p] {
+				add = append(add, p)
+			}
+		}
+		if len(positions)+len(add) > d.config.ParityBytes {
+			break
+		}
+		for _, p := range add {
+			seen[p] = true
+		}
+		positions = append(positions, add...)
+	}
+	return positions
 }
 
 // dotsToBytes converts dot values back into a byte slice.
@@ -93,8 +514,12 @@ func (d *Decoder) dotsToBytes(dots []Dot) []byte {
 	bits := make([]uint8, len(dots)*bitsPerDot)
 
 	for i, dot := range dots {
+		value := dot.Value
+		if d.config.GrayCode {
+			value = toGray(value)
+		}
 		for b := bitsPerDot - 1; b >= 0; b-- {
-			bits[i*bitsPerDot+(bitsPerDot-1-b)] = (dot.Value >> b) & 1
+			bits[i*bitsPerDot+(bitsPerDot-1-b)] = (value >> b) & 1
 		}
 	}
 
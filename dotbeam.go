@@ -39,14 +39,93 @@ type Config struct {
 	// Ring dot counts: 6, 12, 18, 24, ... (6*ring_number).
 	Rings int
 
-	// BitsPerDot is the number of bits per dot color (default: 3 for 8 colors).
+	// BitsPerDot is the number of bits per dot color (default: 3 for 8
+	// colors). 1, 2 and 4 select 2, 4 and 16 colors.
 	BitsPerDot int
 
+	// Palette overrides the dot colors. It must have 1 << BitsPerDot
+	// entries, no two of them the same hue: the scanner matches saturated
+	// dots by hue, so shades of one hue are indistinguishable to it. nil
+	// selects the built-in palette for BitsPerDot.
+	Palette Palette
+
 	// FPS is the frame display rate (default: 5).
 	FPS int
 
 	// UseFountain enables fountain (LT) coding for out-of-order frame tolerance.
+	// Without Checksum the decoder waits for two frames to agree on the
+	// block count K before it starts; frames claiming another K are then
+	// dropped rather than restarting the decode.
 	UseFountain bool
+
+	// Checksum appends a CRC-16 to every frame so misread frames are
+	// rejected with ErrChecksum instead of being stored.
+	Checksum bool
+
+	// ParityBytes is the number of Reed-Solomon parity bytes appended to
+	// every frame (default: 0, disabled). Each pair of parity bytes lets the
+	// decoder correct one misread byte per frame.
+	ParityBytes int
+
+	// IndexBytes selects the frame header. 0 keeps the legacy 2-byte
+	// [index, total] header, limited to 255 frames. 1, 2 or 3 switch to the
+	// versioned header with index and total fields of that many bytes,
+	// allowing up to 255, 65,535 or 16,777,215 frames.
+	IndexBytes int
+
+	// Session tags every frame with a random per-transfer session ID (using
+	// the versioned header). The decoder locks onto one session and rejects
+	// frames from any other transfer. With Checksum set it trusts the first
+	// valid frame's session; without it, it holds frames back until two
+	// agree, so a misread session ID cannot capture the decoder.
+	Session bool
+
+	// Pilots reserves the first dot positions (ring 1 outward) for pilot
+	// dots showing each palette color once, in order. DecodeImage fits a
+	// per-frame color-correction matrix from them before reading the data
+	// dots, which makes tinted lighting far less fragile. Pilots cost one
+	// dot per palette color and are not signalled in the frame. The fit
+	// needs the anchors' white plus at least three pilots, so BitsPerDot
+	// must be 2 or more.
+	Pilots bool
+
+	// GrayCode maps bit groups to palette indices in Gray code, so colors
+	// adjacent in hue (the likeliest camera confusion) differ by exactly
+	// one bit. It is signalled in the versioned header, which it implies.
+	GrayCode bool
+
+	// Preamble prepends an in-band metadata preamble (exact length, content
+	// type, filename) so Decoder.Data returns exactly the original bytes.
+	Preamble bool
+
+	// Compress DEFLATE-compresses the payload whenever that makes it
+	// shorter; the decoder inflates it transparently. Requires Preamble.
+	Compress bool
+
+	// Votes is the number of reads of each frame the decoder collects
+	// before committing a per-dot majority vote (default: 0; 0 or 1 means
+	// the first clean read wins). Each dot takes the value most reads agree
+	// on.
+	Votes int
+
+	// Agreement is the minimum vote confidence (0.0 to 1.0, the mean share
+	// of reads agreeing with each winning dot) a vote must reach before it
+	// is committed. Below it the decoder keeps reading, voting over the
+	// latest Votes reads. 0 accepts any majority. Only used when Votes > 1.
+	Agreement float64
+
+	// TotalReads is the number of frames that must agree on the frame total
+	// (or fountain K) before the decoder locks onto it (default: 0, the
+	// first frame's total is trusted). Frames are held back until then, and
+	// frames claiming any other total are rejected afterwards.
+	TotalReads int
+
+	// ErasureThreshold marks dots whose Dot.Confidence is below it as
+	// erasures, so Reed-Solomon spends one parity byte instead of two on
+	// each byte they touch (default: 0, disabled). Only used with
+	// ParityBytes; set Checksum too, since erasures that use up the parity
+	// leave Reed-Solomon unable to detect a remaining misread.
+	ErasureThreshold float64
 }
 
 // DefaultConfig returns a sensible default configuration.
@@ -70,9 +149,15 @@ type Frame struct {
 type Dot struct {
 	Ring  int     // Ring number (1-indexed)
 	Index int     // Position within ring (0-indexed)
-	Value uint8   // Encoded value (0-7 for 3-bit)
+	Value uint8   // Encoded value: palette index (0-7 for 3-bit)
 	X     float64 // Normalized X position (-1.0 to 1.0)
 	Y     float64 // Normalized Y position (-1.0 to 1.0)
+	Pilot bool    // Pilot dot with a fixed value, carrying no data
+
+	// Confidence is how clearly Value was read: 0.5 for a tie between two
+	// colors up to 1.0 for an exact match, or 0 if unknown. DecodeImage
+	// sets it; encoded frames leave it 0.
+	Confidence float64
 }
 
 // Anchor represents an orientation anchor dot.
@@ -80,13 +165,39 @@ type Anchor struct {
 	X, Y float64
 }
 
-// TotalDots returns the total number of data dots for this config.
+// TotalDots returns the total number of data dots for this config,
+// excluding pilot dots.
 func (c Config) TotalDots() int {
 	total := 0
 	for ring := 1; ring <= c.Rings; ring++ {
 		total += ring * 6
 	}
-	return total
+	return max(0, total-c.pilotCount())
+}
+
+// validate reports whether the config leaves room for payload in a frame
+// and its options are consistent.
+func (c Config) validate() error {
+	if c.IndexBytes < 0 || c.IndexBytes > 3 || c.ParityBytes < 0 || c.BytesPerFrame() <= 0 {
+		return ErrInvalidConfig
+	}
+	if c.BitsPerDot < 1 || c.BitsPerDot > 8 || len(c.Colors()) != 1<<c.BitsPerDot {
+		return ErrInvalidConfig
+	}
+	if !c.Colors().distinctHues() {
+		return ErrInvalidConfig
+	}
+	if c.Pilots && c.pilotCount()+1 < minPilotSamples {
+		return ErrInvalidConfig
+	}
+	if c.Compress && !c.Preamble {
+		return ErrInvalidConfig
+	}
+	if c.Votes < 0 || c.TotalReads < 0 || c.Agreement < 0 || c.Agreement > 1 ||
+		c.ErasureThreshold < 0 || c.ErasureThreshold > 1 {
+		return ErrInvalidConfig
+	}
+	return nil
 }
 
 // BitsPerFrame returns the number of data bits per frame.
@@ -94,8 +205,39 @@ func (c Config) BitsPerFrame() int {
 	return c.TotalDots() * c.BitsPerDot
 }
 
-// BytesPerFrame returns the usable data bytes per frame (excluding header).
+// BytesPerFrame returns the usable data bytes per frame (excluding header
+// and checksum).
 func (c Config) BytesPerFrame() int {
-	// 2 header bytes (frame index + total), rest is payload
-	return (c.BitsPerFrame() / 8) - 2
+	return c.frameBodySize() - c.headerSize()
+}
+
+// frameBodySize returns the header + payload bytes covered by the checksum.
+func (c Config) frameBodySize() int {
+	size := c.frameMessageSize()
+	if c.Checksum {
+		size -= checksumSize
+	}
+	return size
+}
+
+// frameMessageSize returns the frame bytes protected by Reed-Solomon
+// parity: header, payload and checksum.
+func (c Config) frameMessageSize() int {
+	size := c.BitsPerFrame() / 8
+	if c.ParityBytes > 0 {
+		size -= rsCodewords(size) * c.ParityBytes
+	}
+	return size
+}
+
+// headerSize returns the number of header bytes at the start of each frame.
+func (c Config) headerSize() int {
+	size := c.indexWidth() + c.totalWidth()
+	if c.versioned() {
+		size++ // version/flags byte
+	}
+	if c.Session {
+		size += sessionSize
+	}
+	return size
 }
@@ -125,10 +125,37 @@ func TestScaleToCanvas(t *testing.T) {
 
 // --- Encoder tests ---
 
+// mustEncode encodes data and fails the test on error.
+func mustEncode(t *testing.T, enc *Encoder, data []byte) []Frame {
+	t.Helper()
+	frames, err := enc.Encode(data)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	return frames
+}
+
+// roundTrip encodes data with config, feeds every frame to a new decoder
+// and returns the reassembled data, failing the test on any error.
+func roundTrip(t *testing.T, config Config, data []byte) []byte {
+	t.Helper()
+	dec := NewDecoder(config)
+	for _, f := range mustEncode(t, NewEncoder(config), data) {
+		if _, err := dec.AddFrame(f.Dots); err != nil {
+			t.Fatalf("AddFrame(%d) error: %v", f.Index, err)
+		}
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	return got
+}
+
 func TestEncodeSmall(t *testing.T) {
 	enc := NewEncoder(DefaultConfig())
 	data := []byte("hello")
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 
 	if len(frames) != 1 {
 		t.Fatalf("expected 1 frame for 5 bytes, got %d", len(frames))
@@ -151,7 +178,7 @@ func TestEncodeMultiFrame(t *testing.T) {
 	enc := NewEncoder(DefaultConfig())
 	// 20 bytes per frame, so 50 bytes = 3 frames
 	data := bytes.Repeat([]byte("A"), 50)
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 
 	if len(frames) != 3 {
 		t.Fatalf("expected 3 frames for 50 bytes, got %d", len(frames))
@@ -168,7 +195,7 @@ func TestEncodeMultiFrame(t *testing.T) {
 
 func TestEncodeEmpty(t *testing.T) {
 	enc := NewEncoder(DefaultConfig())
-	frames := enc.Encode([]byte{})
+	frames := mustEncode(t, enc, []byte{})
 	if len(frames) != 0 {
 		t.Fatalf("expected 0 frames for empty data, got %d", len(frames))
 	}
@@ -176,7 +203,7 @@ func TestEncodeEmpty(t *testing.T) {
 
 func TestDotValues(t *testing.T) {
 	enc := NewEncoder(DefaultConfig())
-	frames := enc.Encode([]byte("A"))
+	frames := mustEncode(t, enc, []byte("A"))
 
 	for _, d := range frames[0].Dots {
 		if d.Value > 7 {
@@ -193,7 +220,7 @@ func TestDecoderSingleFrame(t *testing.T) {
 	dec := NewDecoder(config)
 
 	data := []byte("hello dotbeam!")
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 
 	for _, f := range frames {
 		done, err := dec.AddFrame(f.Dots)
@@ -223,7 +250,7 @@ func TestRoundTripMultiFrame(t *testing.T) {
 
 	// Exactly 40 bytes = 2 full frames
 	data := bytes.Repeat([]byte("ABCDEFGHIJKLMNOPQRST"), 2)
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 
 	if len(frames) != 2 {
 		t.Fatalf("expected 2 frames, got %d", len(frames))
@@ -256,7 +283,7 @@ func TestRoundTripOutOfOrder(t *testing.T) {
 
 	// 60 bytes = 3 frames
 	data := bytes.Repeat([]byte("X"), 60)
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 
 	// Feed in reverse order
 	for i := len(frames) - 1; i >= 0; i-- {
@@ -279,7 +306,7 @@ func TestDecoderDuplicateFrame(t *testing.T) {
 	dec := NewDecoder(config)
 
 	data := []byte("test")
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 
 	// Add same frame twice — should not double count
 	dec.AddFrame(frames[0].Dots)
@@ -300,7 +327,7 @@ func TestDecoderProgress(t *testing.T) {
 	dec := NewDecoder(config)
 
 	data := bytes.Repeat([]byte("Z"), 60) // 3 frames
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 
 	if dec.Progress() != 0 {
 		t.Errorf("initial progress = %f, want 0", dec.Progress())
@@ -319,7 +346,7 @@ func TestDecoderReset(t *testing.T) {
 	dec := NewDecoder(config)
 
 	data := []byte("reset test")
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 	dec.AddFrame(frames[0].Dots)
 
 	dec.Reset()
@@ -346,7 +373,7 @@ func TestDecoderDataBeforeComplete(t *testing.T) {
 	dec := NewDecoder(config)
 
 	data := bytes.Repeat([]byte("Y"), 60) // 3 frames
-	frames := enc.Encode(data)
+	frames := mustEncode(t, enc, data)
 	dec.AddFrame(frames[0].Dots)
 
 	_, err := dec.Data()
@@ -377,3 +404,120 @@ func dist(a, b Anchor) float64 {
 	dy := a.Y - b.Y
 	return math.Sqrt(dx*dx + dy*dy)
 }
+
+func TestDecoderRejectsIndexBeyondTotal(t *testing.T) {
+	enc := NewEncoder(DefaultConfig())
+	dec := NewDecoder(DefaultConfig())
+
+	f := enc.frame(5, 3, []byte("stray"))
+	if _, err := dec.AddFrame(f.Dots); err != ErrInvalidFrame {
+		t.Fatalf("index 5 of 3: err = %v, want ErrInvalidFrame", err)
+	}
+}
+
+func TestTotalConsensusIgnoresBadHeader(t *testing.T) {
+	config := DefaultConfig()
+	config.TotalReads = 3
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("consensus "), 8)
+	frames := mustEncode(t, enc, data)
+	if len(frames) != 4 {
+		t.Fatalf("expected 4 frames, got %d", len(frames))
+	}
+
+	// A misread header claiming a 1-frame transfer must not complete it.
+	bad := enc.frame(0, 1, frames[0].Payload)
+	if done, err := dec.AddFrame(bad.Dots); done || err != nil {
+		t.Fatalf("bad header: done = %v, err = %v; want held", done, err)
+	}
+	if dec.Progress() != 0 {
+		t.Fatalf("expected progress 0 before the total locks, got %f", dec.Progress())
+	}
+
+	for i, f := range frames {
+		done, err := dec.AddFrame(f.Dots)
+		if err != nil {
+			t.Fatalf("AddFrame(%d) error: %v", i, err)
+		}
+		if want := i == len(frames)-1; done != want {
+			t.Fatalf("AddFrame(%d) done = %v, want %v", i, done, want)
+		}
+		if i == 1 && dec.Progress() != 0 {
+			t.Fatalf("total locked after %d agreeing reads", i+1)
+		}
+	}
+
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatalf("round-trip failed: got %q, want prefix %q", got, data)
+	}
+}
+
+func TestTotalLocksByDefault(t *testing.T) {
+	config := DefaultConfig()
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("first total "), 6)
+	frames := mustEncode(t, enc, data)
+	if len(frames) != 4 {
+		t.Fatalf("expected 4 frames, got %d", len(frames))
+	}
+	dec.AddFrame(frames[0].Dots)
+
+	// A header corrupted to claim 3 frames must not change the total.
+	bad := enc.frame(1, 3, frames[1].Payload)
+	if done, err := dec.AddFrame(bad.Dots); done || err != ErrInvalidFrame {
+		t.Fatalf("corrupted total: (%v, %v), want (false, ErrInvalidFrame)", done, err)
+	}
+	for _, f := range frames[2:] {
+		if done, _ := dec.AddFrame(f.Dots); done {
+			t.Fatal("transfer complete with frame 1 missing")
+		}
+	}
+	if p := dec.Progress(); p != 0.75 {
+		t.Fatalf("progress = %f, want 0.75", p)
+	}
+
+	if done, err := dec.AddFrame(frames[1].Dots); !done || err != nil {
+		t.Fatalf("AddFrame(1) = (%v, %v), want (true, nil)", done, err)
+	}
+	if got, err := dec.Data(); err != nil || !bytes.HasPrefix(got, data) {
+		t.Fatalf("Data() = %q, %v; want prefix %q", got, err, data)
+	}
+}
+
+func TestTotalConflict(t *testing.T) {
+	config := DefaultConfig()
+	config.TotalReads = 2
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	a := mustEncode(t, enc, bytes.Repeat([]byte("A"), 70))
+	b := mustEncode(t, enc, bytes.Repeat([]byte("B"), 30))
+	if len(a) == len(b) {
+		t.Fatal("test transfers need different frame totals")
+	}
+	dec.AddFrame(a[0].Dots)
+	dec.AddFrame(a[1].Dots)
+
+	// Frames of the other transfer are rejected, then flagged once they
+	// outnumber the locked total.
+	for i, want := range []error{ErrInvalidFrame, ErrInvalidFrame, ErrTotalConflict} {
+		if _, err := dec.AddFrame(b[i%len(b)].Dots); err != want {
+			t.Fatalf("read %d of other transfer: err = %v, want %v", i, err, want)
+		}
+	}
+
+	dec.Reset()
+	dec.AddFrame(b[0].Dots)
+	dec.AddFrame(b[1].Dots)
+	if dec.Progress() != 1.0 {
+		t.Fatalf("expected progress 1.0 after Reset, got %f", dec.Progress())
+	}
+}
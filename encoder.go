@@ -1,9 +1,16 @@
 package dotbeam
 
+import "crypto/ed25519"
+
 // Encoder converts arbitrary bytes into a sequence of dotbeam frames.
 type Encoder struct {
-	config Config
-	layout Layout
+	config  Config
+	layout  Layout
+	meta    Metadata
+	session uint16 // session ID of the most recent transfer
+	secret  secret // encryption key, if any
+
+	signKey ed25519.PrivateKey // signing key, if any
 }
 
 // NewEncoder creates a new encoder with the given config.
@@ -13,16 +20,27 @@ func NewEncoder(config Config) *Encoder {
 }
 
 // Encode splits data into frames, each containing dot colors.
-func (e *Encoder) Encode(data []byte) []Frame {
-	bytesPerFrame := e.config.BytesPerFrame()
-	if bytesPerFrame <= 0 {
-		return nil
+// With Config.UseFountain set, it returns a loopable carousel of LT-coded
+// frames instead; use Fountain for an endless stream. It returns
+// ErrTooLarge if data needs more frames than the header can address.
+func (e *Encoder) Encode(data []byte) ([]Frame, error) {
+	if err := e.config.validate(); err != nil {
+		return nil, err
 	}
+	if e.config.UseFountain {
+		return e.encodeFountain(data)
+	}
+	data, err := e.transferBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	e.session = newSessionID()
 
 	// Calculate total frames needed
+	bytesPerFrame := e.config.BytesPerFrame()
 	totalFrames := (len(data) + bytesPerFrame - 1) / bytesPerFrame
-	if totalFrames > 255 {
-		totalFrames = 255 // Protocol limit
+	if totalFrames > e.config.MaxFrames() {
+		return nil, ErrTooLarge
 	}
 
 	frames := make([]Frame, totalFrames)
@@ -32,35 +50,53 @@ func (e *Encoder) Encode(data []byte) []Frame {
 		if end > len(data) {
 			end = len(data)
 		}
-		chunk := data[start:end]
-
-		// Build the full frame bytes: [index, total, ...payload]
-		frameBytes := make([]byte, 2+len(chunk))
-		frameBytes[0] = byte(i)
-		frameBytes[1] = byte(totalFrames)
-		copy(frameBytes[2:], chunk)
-
-		// Pad to fill all dots if needed (ceiling division to preserve trailing bits)
-		totalBits := e.config.BitsPerFrame()
-		totalBytes := (totalBits + 7) / 8
-		if len(frameBytes) < totalBytes {
-			padded := make([]byte, totalBytes)
-			copy(padded, frameBytes)
-			frameBytes = padded
-		}
+		frames[i] = e.frame(i, totalFrames, data[start:end])
+	}
 
-		// Convert bytes to dot values
-		dots := e.bytesToDots(frameBytes)
+	return frames, nil
+}
 
-		frames[i] = Frame{
-			Index:   i,
-			Total:   totalFrames,
-			Dots:    dots,
-			Payload: chunk,
-		}
+// frame builds the regular (non-fountain) frame carrying chunk.
+func (e *Encoder) frame(index, total int, chunk []byte) Frame {
+	// Build the full frame bytes: [header, ...payload]
+	h := frameHeader{index: index, total: total, session: e.session}
+	frameBytes := append(e.config.putHeader(h), chunk...)
+
+	return Frame{
+		Index:   index,
+		Total:   total,
+		Dots:    e.frameDots(frameBytes),
+		Payload: chunk,
 	}
+}
 
-	return frames
+// SessionID returns the session ID stamped on the frames of the most recent
+// Encode or Fountain call. Only meaningful with Config.Session set.
+func (e *Encoder) SessionID() uint16 {
+	return e.session
+}
+
+// frameDots appends the checksum and Reed-Solomon parity (if enabled),
+// pads the full frame bytes to fill every dot and converts them to
+// positioned dot values.
+func (e *Encoder) frameDots(frameBytes []byte) []Dot {
+	if e.config.Checksum {
+		frameBytes = appendChecksum(frameBytes, e.config.frameBodySize())
+	}
+	if e.config.ParityBytes > 0 {
+		frameBytes = rsEncodeFrame(frameBytes, e.config.BitsPerFrame()/8, e.config.ParityBytes)
+	}
+
+	// Pad to fill all dots if needed (ceiling division to preserve trailing bits)
+	totalBits := e.config.BitsPerFrame()
+	totalBytes := (totalBits + 7) / 8
+	if len(frameBytes) < totalBytes {
+		padded := make([]byte, totalBytes)
+		copy(padded, frameBytes)
+		frameBytes = padded
+	}
+
+	return e.bytesToDots(frameBytes)
 }
 
 // bytesToDots converts a byte slice into dot values using the layout positions.
@@ -68,10 +104,24 @@ func (e *Encoder) bytesToDots(data []byte) []Dot {
 	bits := bytesToBits(data)
 	bitsPerDot := e.config.BitsPerDot
 	dotIndex := 0
+	pilot := uint8(0)
 	var dots []Dot
 
 	for _, ring := range e.layout.Rings {
 		for j, pos := range ring.Positions {
+			if pos.Pilot {
+				dots = append(dots, Dot{
+					Ring:  ring.DotCount / 6,
+					Index: j,
+					Value: pilot,
+					X:     pos.X,
+					Y:     pos.Y,
+					Pilot: true,
+				})
+				pilot++
+				continue
+			}
+
 			bitStart := dotIndex * bitsPerDot
 			if bitStart+bitsPerDot > len(bits) {
 				break
@@ -81,6 +131,9 @@ func (e *Encoder) bytesToDots(data []byte) []Dot {
 			for b := 0; b < bitsPerDot; b++ {
 				value = (value << 1) | bits[bitStart+b]
 			}
+			if e.config.GrayCode {
+				value = fromGray(value)
+			}
 
 			dots = append(dots, Dot{
 				Ring:  ring.DotCount / 6, // Ring number
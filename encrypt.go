@@ -0,0 +1,156 @@
+package dotbeam
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+)
+
+// Payload encryption.
+//
+// With a passphrase or key set, the encoder seals the payload with
+// AES-256-GCM before framing. The salt and nonce travel in the preamble,
+// and the whole preamble is authenticated as additional data, so metadata
+// cannot be altered either. Content type, filename and original length
+// stay readable.
+
+// PBKDF2-HMAC-SHA256 parameters for passphrase-derived keys.
+const (
+	kdfIterations = 600_000
+	kdfSaltSize   = 16
+	kdfKeySize    = 32
+)
+
+// secret holds the key material shared by Encoder and Decoder. At most one
+// of key and passphrase is set.
+type secret struct {
+	key        []byte
+	passphrase string
+
+	// Last passphrase derivation, cached so repeated Data calls are cheap.
+	salt    []byte
+	derived []byte
+}
+
+func (s *secret) enabled() bool {
+	return s.key != nil || s.passphrase != ""
+}
+
+func (s *secret) setPassphrase(passphrase string) {
+	*s = secret{passphrase: passphrase}
+}
+
+func (s *secret) setKey(key []byte) error {
+	if key == nil {
+		*s = secret{}
+		return nil
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return ErrInvalidKey
+	}
+	*s = secret{key: append([]byte(nil), key...)}
+	return nil
+}
+
+// keyFor returns the AES key for a transfer, or nil if derivation fails.
+// salt is nil for transfers sealed with a raw key.
+func (s *secret) keyFor(salt []byte) []byte {
+	if s.passphrase == "" || salt == nil {
+		return s.key
+	}
+	if s.derived == nil || !bytes.Equal(s.salt, salt) {
+		key, err := pbkdf2.Key(sha256.New, s.passphrase, salt, kdfIterations, kdfKeySize)
+		if err != nil {
+			return nil
+		}
+		s.salt = append([]byte(nil), salt...)
+		s.derived = key
+	}
+	return s.derived
+}
+
+// SetPassphrase encrypts subsequent transfers with a key derived from
+// passphrase (PBKDF2-HMAC-SHA256, random salt per transfer). An empty
+// passphrase disables encryption. Requires Config.Preamble.
+func (e *Encoder) SetPassphrase(passphrase string) {
+	e.secret.setPassphrase(passphrase)
+}
+
+// SetKey encrypts subsequent transfers with a shared AES key of 16, 24 or
+// 32 bytes. A nil key disables encryption. Requires Config.Preamble.
+func (e *Encoder) SetKey(key []byte) error {
+	return e.secret.setKey(key)
+}
+
+// SetPassphrase sets the passphrase used to decrypt transfers. With it
+// set, plaintext transfers are refused with ErrNotEncrypted.
+func (d *Decoder) SetPassphrase(passphrase string) {
+	d.secret.setPassphrase(passphrase)
+}
+
+// SetKey sets the shared AES key used to decrypt transfers. With it set,
+// plaintext transfers are refused with ErrNotEncrypted.
+func (d *Decoder) SetKey(key []byte) error {
+	return d.secret.setKey(key)
+}
+
+// seal encrypts plaintext, recording the salt, nonce and ciphertext length
+// in p. The marshalled preamble is the additional authenticated data.
+func (e *Encoder) seal(p *preamble, plaintext []byte) ([]byte, error) {
+	if e.secret.passphrase != "" {
+		p.salt = make([]byte, kdfSaltSize)
+		rand.Read(p.salt)
+	}
+	gcm, err := newGCM(e.secret.keyFor(p.salt))
+	if err != nil {
+		return nil, err
+	}
+	p.nonce = make([]byte, gcm.NonceSize())
+	rand.Read(p.nonce)
+	p.flags |= flagEncrypted
+	p.Length = len(plaintext) + gcm.Overhead()
+	return gcm.Seal(nil, p.nonce, plaintext, marshalPreamble(*p)), nil
+}
+
+// checkEncrypted refuses a plaintext transfer when a secret is set: anyone
+// can put plaintext frames in front of the camera, so a receiver expecting
+// an encrypted transfer must not accept one silently.
+func (d *Decoder) checkEncrypted(p preamble) error {
+	if d.secret.enabled() && p.flags&flagEncrypted == 0 {
+		return ErrNotEncrypted
+	}
+	return nil
+}
+
+// open decrypts an encrypted payload. header is the raw preamble, which
+// was authenticated when the payload was sealed.
+func (d *Decoder) open(p preamble, header, ciphertext []byte) ([]byte, error) {
+	if !d.secret.enabled() {
+		return nil, ErrKeyRequired
+	}
+	gcm, err := newGCM(d.secret.keyFor(p.salt))
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	if len(p.nonce) != gcm.NonceSize() {
+		return nil, ErrInvalidMetadata
+	}
+	plaintext, err := gcm.Open(nil, p.nonce, ciphertext, header)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
+
+// newGCM returns AES-GCM for key. It returns ErrInvalidKey if key is nil,
+// as it is when passphrase derivation fails, or not a valid AES key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,177 @@
+package dotbeam
+
+import (
+	"bytes"
+	"testing"
+)
+
+// decodeAll feeds frames into dec and returns Data().
+func decodeAll(dec *Decoder, frames []Frame) ([]byte, error) {
+	for _, f := range frames {
+		dec.AddFrame(f.Dots)
+	}
+	return dec.Data()
+}
+
+func TestEncryptPassphrase(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.SetPassphrase("correct horse")
+	enc.SetMetadata(Metadata{ContentType: "text/plain"})
+
+	secret := []byte("wifi password: hunter2")
+	frames := mustEncode(t, enc, secret)
+
+	// Nothing on screen may reveal the plaintext.
+	var onScreen []byte
+	for _, f := range frames {
+		onScreen = append(onScreen, f.Payload...)
+	}
+	if bytes.Contains(onScreen, []byte("hunter2")) {
+		t.Fatal("plaintext visible in frame payloads")
+	}
+
+	dec := NewDecoder(config)
+	dec.SetPassphrase("correct horse")
+	got, err := decodeAll(dec, frames)
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Fatalf("round-trip failed:\n got: %q\nwant: %q", got, secret)
+	}
+	if meta, _ := dec.Metadata(); meta.ContentType != "text/plain" {
+		t.Errorf("ContentType = %q, want text/plain", meta.ContentType)
+	}
+}
+
+func TestEncryptWrongKey(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.SetPassphrase("correct horse")
+	frames := mustEncode(t, enc, []byte("top secret"))
+
+	dec := NewDecoder(config)
+	dec.SetPassphrase("battery staple")
+	if _, err := decodeAll(dec, frames); err != ErrWrongKey {
+		t.Errorf("wrong passphrase: err = %v, want ErrWrongKey", err)
+	}
+
+	dec = NewDecoder(config)
+	if _, err := decodeAll(dec, frames); err != ErrKeyRequired {
+		t.Errorf("no passphrase: err = %v, want ErrKeyRequired", err)
+	}
+
+	dec = NewDecoder(config)
+	dec.SetKey(make([]byte, 32))
+	if _, err := decodeAll(dec, frames); err != ErrWrongKey {
+		t.Errorf("raw key for passphrase transfer: err = %v, want ErrWrongKey", err)
+	}
+}
+
+func TestEncryptRefusesPlaintext(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	frames := mustEncode(t, NewEncoder(config), []byte("attacker payload"))
+
+	dec := NewDecoder(config)
+	dec.SetPassphrase("correct horse")
+	if got, err := decodeAll(dec, frames); err != ErrNotEncrypted {
+		t.Errorf("plaintext with passphrase set: (%q, %v), want ErrNotEncrypted", got, err)
+	}
+
+	dec = NewDecoder(config)
+	dec.SetKey(make([]byte, 32))
+	if got, err := decodeAll(dec, frames); err != ErrNotEncrypted {
+		t.Errorf("plaintext with key set: (%q, %v), want ErrNotEncrypted", got, err)
+	}
+
+	var buf bytes.Buffer
+	dec = NewStreamDecoder(config, &buf)
+	dec.SetPassphrase("correct horse")
+	var err error
+	for _, f := range frames {
+		if _, err = dec.AddFrame(f.Dots); err != nil {
+			break
+		}
+	}
+	if err != ErrNotEncrypted || buf.Len() != 0 {
+		t.Errorf("stream decoder: err = %v, wrote %q; want ErrNotEncrypted, nothing", err, buf.Bytes())
+	}
+}
+
+func TestEncryptSharedKey(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	config.UseFountain = true
+	key := bytes.Repeat([]byte{0x42}, 16)
+
+	enc := NewEncoder(config)
+	if err := enc.SetKey(key); err != nil {
+		t.Fatalf("SetKey error: %v", err)
+	}
+	data := bytes.Repeat([]byte("token "), 20)
+	frames := mustEncode(t, enc, data)
+
+	dec := NewDecoder(config)
+	dec.SetKey(key)
+	got, err := decodeAll(dec, frames)
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("round-trip failed")
+	}
+
+	if err := enc.SetKey([]byte("short")); err != ErrInvalidKey {
+		t.Errorf("SetKey(5 bytes) = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestEncryptTamperedMetadata(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.SetPassphrase("pw")
+	enc.SetMetadata(Metadata{Filename: "pay-alice.txt"})
+	stream, err := enc.transferBytes([]byte("send 10 to alice"))
+	if err != nil {
+		t.Fatalf("transferBytes error: %v", err)
+	}
+
+	// The filename is public but authenticated: changing it must fail.
+	i := bytes.Index(stream, []byte("alice"))
+	copy(stream[i:], "mallo")
+
+	dec := NewDecoder(config)
+	dec.SetPassphrase("pw")
+	frames := mustEncode(t, NewEncoder(DefaultConfig()), stream)
+	if _, err := decodeAll(dec, frames); err != ErrWrongKey {
+		t.Errorf("tampered filename: err = %v, want ErrWrongKey", err)
+	}
+}
+
+func TestEncryptRequiresPreamble(t *testing.T) {
+	enc := NewEncoder(DefaultConfig())
+	enc.SetPassphrase("pw")
+	if _, err := enc.Encode([]byte("x")); err != ErrInvalidConfig {
+		t.Errorf("Encode without preamble = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestEncryptUnusableKey(t *testing.T) {
+	if _, err := newGCM(nil); err != ErrInvalidKey {
+		t.Errorf("newGCM(nil) = %v, want ErrInvalidKey", err)
+	}
+
+	// A key that bypassed SetKey must fail the encode, not panic.
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.secret = secret{key: []byte("short")}
+	if _, err := enc.Encode([]byte("x")); err != ErrInvalidKey {
+		t.Errorf("Encode with a 5-byte key = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,380 @@
+package dotbeam
+
+import "math"
+
+// LT (Luby Transform) fountain coding.
+//
+// The payload is split into K source blocks of BytesPerFrame bytes. Each
+// fountain frame carries one encoded symbol: the XOR of a pseudo-randomly
+// chosen subset of source blocks. The subset is derived entirely from the
+// frame's seed and K, so the receiver can rebuild it from the header alone.
+// Any K+ε symbols are enough to recover the payload with a peeling decoder.
+
+// Robust soliton parameters. c tunes the expected number of degree-1
+// symbols in flight; delta bounds the decoding failure probability.
+const (
+	ltC     = 0.1
+	ltDelta = 0.5
+)
+
+// FountainEncoder produces an endless stream of LT-coded frames for a
+// single payload. Create one with Encoder.Fountain.
+type FountainEncoder struct {
+	enc     *Encoder
+	blocks  [][]byte
+	cdf     []float64
+	seed    int
+	mask    int    // largest seed the header can carry
+	session uint16 // session ID stamped on every symbol
+}
+
+// Fountain returns an endless LT frame source for data. Every call to Next
+// yields a fresh symbol; receivers can join at any point and need only
+// slightly more than K of them, in any order. It returns nil if data is
+// empty and ErrTooLarge if K exceeds what the header can address.
+func (e *Encoder) Fountain(data []byte) (*FountainEncoder, error) {
+	if err := e.config.validate(); err != nil {
+		return nil, err
+	}
+	data, err := e.transferBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	bytesPerFrame := e.config.BytesPerFrame()
+	k := (len(data) + bytesPerFrame - 1) / bytesPerFrame
+	if k > e.config.MaxFrames() {
+		return nil, ErrTooLarge
+	}
+
+	blocks := make([][]byte, k)
+	for i := range blocks {
+		block := make([]byte, bytesPerFrame)
+		start := i * bytesPerFrame
+		end := start + bytesPerFrame
+		if end > len(data) {
+			end = len(data)
+		}
+		copy(block, data[start:end])
+		blocks[i] = block
+	}
+
+	e.session = newSessionID()
+	return &FountainEncoder{
+		enc:     e,
+		blocks:  blocks,
+		cdf:     robustSolitonCDF(k),
+		mask:    e.config.maxSeed(),
+		session: e.session,
+	}, nil
+}
+
+// K returns the number of source blocks.
+func (f *FountainEncoder) K() int {
+	return len(f.blocks)
+}
+
+// Next returns the next encoded frame in the stream. Seeds wrap around
+// after 65,535 frames (16,777,215 with a 3-byte header).
+func (f *FountainEncoder) Next() Frame {
+	frame := f.Symbol(f.seed)
+	f.seed = (f.seed + 1) & f.mask
+	return frame
+}
+
+// Symbol returns the encoded frame for a specific seed.
+func (f *FountainEncoder) Symbol(seed int) Frame {
+	k := len(f.blocks)
+	seed &= f.mask
+
+	symbol := make([]byte, len(f.blocks[0]))
+	for _, n := range ltNeighbors(seed, f.cdf) {
+		xorInto(symbol, f.blocks[n])
+	}
+
+	// Build the full frame bytes: [header(seed, k), ...symbol]
+	h := frameHeader{index: seed, total: k, session: f.session}
+	frameBytes := append(f.enc.config.putHeader(h), symbol...)
+
+	return Frame{
+		Index:   seed,
+		Total:   k,
+		Dots:    f.enc.frameDots(frameBytes),
+		Payload: symbol,
+	}
+}
+
+// encodeFountain returns a finite carousel of fountain frames for data.
+// Frames are generated until a peeling decoder fed the carousel in order
+// completes, plus a margin so a receiver that misses a few frames still
+// decodes within one loop.
+func (e *Encoder) encodeFountain(data []byte) ([]Frame, error) {
+	f, err := e.Fountain(data)
+	if f == nil {
+		return nil, err
+	}
+
+	k := f.K()
+	sim := newLTDecoder(k, len(f.blocks[0]))
+	var frames []Frame
+	for !sim.complete() && len(frames) <= f.mask {
+		frame := f.Next()
+		sim.add(frame.Index, frame.Payload)
+		frames = append(frames, frame)
+	}
+
+	margin := k/4 + 1
+	for i := 0; i < margin && len(frames) <= f.mask; i++ {
+		frames = append(frames, f.Next())
+	}
+	return frames, nil
+}
+
+// ltDecoder recovers source blocks from LT symbols by peeling: any symbol
+// with exactly one unknown neighbour reveals that block, which is then
+// XORed out of every other pending symbol.
+type ltDecoder struct {
+	k         int
+	blockSize int
+	cdf       []float64
+	blocks    [][]byte // recovered source blocks, nil until known or once released
+	released  int      // blocks below this index were released
+	recovered int
+	pending   []*ltSymbol
+	seen      map[int]bool
+}
+
+type ltSymbol struct {
+	data      []byte
+	neighbors []int // unknown source blocks still XORed into data
+}
+
+func newLTDecoder(k, blockSize int) *ltDecoder {
+	return &ltDecoder{
+		k:         k,
+		blockSize: blockSize,
+		cdf:       robustSolitonCDF(k),
+		blocks:    make([][]byte, k),
+		seen:      make(map[int]bool),
+	}
+}
+
+// add feeds one encoded symbol into the decoder. Duplicate seeds are ignored.
+func (d *ltDecoder) add(seed int, data []byte) {
+	if d.seen[seed] || d.complete() {
+		return
+	}
+	d.seen[seed] = true
+
+	neighbors := ltNeighbors(seed, d.cdf)
+	for _, n := range neighbors {
+		if n < d.released {
+			// A released block can no longer be XORed out.
+			return
+		}
+	}
+	sym := &ltSymbol{data: make([]byte, d.blockSize), neighbors: neighbors}
+	copy(sym.data, data)
+	d.pending = append(d.pending, sym)
+	d.peel()
+}
+
+// release frees the recovered blocks below n once they have been handed
+// off. Pending symbols never refer to a recovered block, since peel XORs
+// each one out as soon as it is known; later symbols that refer to a
+// released block are dropped by add.
+func (d *ltDecoder) release(n int) {
+	for ; d.released < n; d.released++ {
+		d.blocks[d.released] = nil
+	}
+}
+
+// peel repeatedly reduces pending symbols against recovered blocks until
+// no further block can be released.
+func (d *ltDecoder) peel() {
+	for progress := true; progress; {
+		progress = false
+		kept := d.pending[:0]
+		for _, s := range d.pending {
+			unknown := s.neighbors[:0]
+			for _, n := range s.neighbors {
+				if d.blocks[n] != nil {
+					xorInto(s.data, d.blocks[n])
+				} else {
+					unknown = append(unknown, n)
+				}
+			}
+			s.neighbors = unknown
+
+			switch len(s.neighbors) {
+			case 0:
+				// Fully redundant, drop it.
+			case 1:
+				d.blocks[s.neighbors[0]] = s.data
+				d.recovered++
+				progress = true
+			default:
+				kept = append(kept, s)
+			}
+		}
+		d.pending = kept
+	}
+}
+
+func (d *ltDecoder) complete() bool {
+	return d.recovered >= d.k
+}
+
+// data concatenates the recovered source blocks.
+func (d *ltDecoder) data() []byte {
+	result := make([]byte, 0, d.k*d.blockSize)
+	for _, b := range d.blocks {
+		result = append(result, b...)
+	}
+	return result
+}
+
+// ltNeighbors returns the distinct source block indices combined into the
+// symbol with the given seed. cdf is the degree distribution for K source
+// blocks, as returned by robustSolitonCDF(K).
+func ltNeighbors(seed int, cdf []float64) []int {
+	k := len(cdf)
+	rng := newLTRand(uint32(seed))
+	degree := ltDegree(rng.float(), cdf)
+
+	// Partial Fisher-Yates shuffle over [0, k), tracking only the
+	// swapped slots so the cost is O(degree) rather than O(k).
+	swapped := make(map[int]int, degree)
+	at := func(i int) int {
+		if v, ok := swapped[i]; ok {
+			return v
+		}
+		return i
+	}
+	neighbors := make([]int, degree)
+	for i := 0; i < degree; i++ {
+		j := i + rng.intn(k-i)
+		neighbors[i] = at(j)
+		swapped[j] = at(i)
+	}
+	return neighbors
+}
+
+// ltDegree maps a uniform sample u in [0, 1) to a degree using a
+// cumulative degree distribution.
+func ltDegree(u float64, cdf []float64) int {
+	lo, hi := 0, len(cdf)-1
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if u < cdf[mid] {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo + 1
+}
+
+// robustSolitonCDF returns the cumulative robust soliton distribution,
+// where cdf[d-1] = P(degree <= d).
+func robustSolitonCDF(k int) []float64 {
+	if k <= 1 {
+		return []float64{1}
+	}
+
+	kf := float64(k)
+	r := ltC * math.Log(kf/ltDelta) * math.Sqrt(kf)
+	pivot := int(math.Round(kf / r))
+	if pivot < 1 {
+		pivot = 1
+	}
+	if pivot > k {
+		pivot = k
+	}
+
+	weights := make([]float64, k)
+	sum := 0.0
+	for d := 1; d <= k; d++ {
+		// Ideal soliton
+		var rho float64
+		if d == 1 {
+			rho = 1 / kf
+		} else {
+			rho = 1 / (float64(d) * float64(d-1))
+		}
+
+		// Robust correction
+		var tau float64
+		switch {
+		case d < pivot:
+			tau = r / (float64(d) * kf)
+		case d == pivot:
+			tau = r * math.Log(r/ltDelta) / kf
+		}
+		if tau < 0 {
+			tau = 0
+		}
+
+		weights[d-1] = rho + tau
+		sum += weights[d-1]
+	}
+
+	cdf := make([]float64, k)
+	acc := 0.0
+	for i, w := range weights {
+		acc += w / sum
+		cdf[i] = acc
+	}
+	cdf[k-1] = 1
+	return cdf
+}
+
+// ltRand is a small xorshift32 generator. It is deliberately simple so
+// that other implementations (JS scanner) can reproduce it bit-for-bit.
+type ltRand struct {
+	state uint32
+}
+
+func newLTRand(seed uint32) *ltRand {
+	s := seed*0x9E3779B1 ^ 0xA5A5A5A5
+	if s == 0 {
+		s = 0x6D2B79F5
+	}
+	r := &ltRand{state: s}
+	// Warm up so adjacent seeds diverge.
+	for i := 0; i < 4; i++ {
+		r.next()
+	}
+	return r
+}
+
+func (r *ltRand) next() uint32 {
+	x := r.state
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	r.state = x
+	return x
+}
+
+// float returns a uniform value in [0, 1).
+func (r *ltRand) float() float64 {
+	return float64(r.next()) / (1 << 32)
+}
+
+// intn returns a uniform value in [0, n).
+func (r *ltRand) intn(n int) int {
+	return int(uint64(r.next()) * uint64(n) >> 32)
+}
+
+// xorInto XORs src into dst in place.
+func xorInto(dst, src []byte) {
+	for i := range dst {
+		if i < len(src) {
+			dst[i] ^= src[i]
+		}
+	}
+}
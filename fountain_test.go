@@ -0,0 +1,296 @@
+package dotbeam
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// mustFountain creates a fountain encoder and fails the test on error.
+func mustFountain(t *testing.T, enc *Encoder, data []byte) *FountainEncoder {
+	t.Helper()
+	f, err := enc.Fountain(data)
+	if err != nil {
+		t.Fatalf("Fountain error: %v", err)
+	}
+	return f
+}
+
+func TestFountainBytesPerFrame(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	// 180 bits / 8 = 22 bytes - 3 header = 19
+	if got := config.BytesPerFrame(); got != 19 {
+		t.Fatalf("expected 19 bytes per fountain frame, got %d", got)
+	}
+}
+
+func TestFountainRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := []byte("The quick brown fox jumps over the lazy dog, twice over and then some more.")
+	frames := mustEncode(t, enc, data)
+	if len(frames) == 0 {
+		t.Fatal("encoder produced no frames")
+	}
+
+	done := false
+	for _, f := range frames {
+		var err error
+		done, err = dec.AddFrame(f.Dots)
+		if err != nil {
+			t.Fatalf("AddFrame(seed %d) error: %v", f.Index, err)
+		}
+		if done {
+			break
+		}
+	}
+	if !done {
+		t.Fatal("carousel did not decode in one loop")
+	}
+
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatalf("round-trip failed:\n got: %q\nwant prefix: %q", got, data)
+	}
+}
+
+func TestFountainMissedFrames(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("0123456789"), 30) // 300 bytes, K = 16
+	f := mustFountain(t, enc, data)
+	k := f.K()
+
+	// Drop every third symbol and feed the rest. The receiver should
+	// finish well before it has seen 3K symbols.
+	used := 0
+	for seed := 0; seed < 10*k; seed++ {
+		if seed%3 == 0 {
+			continue
+		}
+		used++
+		done, err := dec.AddFrame(f.Symbol(seed).Dots)
+		if err != nil {
+			t.Fatalf("AddFrame(seed %d) error: %v", seed, err)
+		}
+		if done {
+			break
+		}
+	}
+
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error after %d symbols: %v", used, err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatal("round-trip with missed frames failed")
+	}
+	if used > 3*k {
+		t.Errorf("needed %d symbols for K=%d", used, k)
+	}
+}
+
+func TestFountainJoinMidStream(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("Z"), 100)
+	f := mustFountain(t, enc, data)
+
+	// Start far into the stream, past any finite carousel.
+	for seed := 1000; seed < 2000; seed++ {
+		if done, _ := dec.AddFrame(f.Symbol(seed).Dots); done {
+			break
+		}
+	}
+
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatal("mid-stream round-trip failed")
+	}
+}
+
+func TestFountainSingleBlock(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	config.Checksum = true // trust K from the first frame
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	frames := mustEncode(t, enc, []byte("hi"))
+	if frames[0].Total != 1 {
+		t.Fatalf("expected K=1, got %d", frames[0].Total)
+	}
+	done, err := dec.AddFrame(frames[0].Dots)
+	if err != nil || !done {
+		t.Fatalf("AddFrame = (%v, %v), want (true, nil)", done, err)
+	}
+}
+
+func TestFountainProgress(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	f := mustFountain(t, enc, bytes.Repeat([]byte("P"), 200))
+	last := 0.0
+	for seed := 0; seed < 100; seed++ {
+		dec.AddFrame(f.Symbol(seed).Dots)
+		p := dec.Progress()
+		if p < last {
+			t.Fatalf("progress went backwards: %f → %f", last, p)
+		}
+		last = p
+	}
+	if last != 1.0 {
+		t.Errorf("final progress = %f, want 1.0", last)
+	}
+
+	dec.Reset()
+	if dec.Progress() != 0 {
+		t.Errorf("progress after reset = %f, want 0", dec.Progress())
+	}
+}
+
+func TestFountainTotalReads(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	config.TotalReads = 4
+
+	data := bytes.Repeat([]byte("locked K "), 10)
+	if got := roundTrip(t, config, data); !bytes.HasPrefix(got, data) {
+		t.Fatalf("round-trip failed: got %q, want prefix %q", got, data)
+	}
+}
+
+func TestFountainKeepsProgressOnBadK(t *testing.T) {
+	for _, checksum := range []bool{false, true} {
+		config := DefaultConfig()
+		config.UseFountain = true
+		config.Checksum = checksum
+		enc := NewEncoder(config)
+		dec := NewDecoder(config)
+
+		data := bytes.Repeat([]byte("keep progress "), 30)
+		f := mustFountain(t, enc, data)
+		other := mustFountain(t, NewEncoder(config), data[:100])
+
+		seed := 0
+		for ; dec.Progress() < 0.5; seed++ {
+			if _, err := dec.AddFrame(f.Symbol(seed).Dots); err != nil {
+				t.Fatalf("checksum %v: AddFrame(seed %d) error: %v", checksum, seed, err)
+			}
+		}
+		before := dec.Progress()
+		for s := range 3 {
+			if _, err := dec.AddFrame(other.Symbol(s).Dots); err != ErrInvalidFrame {
+				t.Fatalf("checksum %v: symbol with another K: err = %v, want ErrInvalidFrame", checksum, err)
+			}
+		}
+		if dec.Progress() != before {
+			t.Fatalf("checksum %v: progress %f → %f after symbols with another K", checksum, before, dec.Progress())
+		}
+
+		for ; seed < 10*f.K(); seed++ {
+			if done, _ := dec.AddFrame(f.Symbol(seed).Dots); done {
+				break
+			}
+		}
+		if got, err := dec.Data(); err != nil || !bytes.HasPrefix(got, data) || dec.Progress() != 1 {
+			t.Fatalf("checksum %v: Data() = %q, %v, progress %f; want prefix %q", checksum, got, err, dec.Progress(), data)
+		}
+	}
+}
+
+func TestFountainUntrustedK(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	config.IndexBytes = 3
+	enc := NewEncoder(config)
+	data := bytes.Repeat([]byte("K"), 100)
+	f := mustFountain(t, enc, data)
+
+	// A misread header claiming the largest K the header can carry.
+	bogus := frameHeader{index: 0, total: config.MaxFrames()}
+	frame := append(config.putHeader(bogus), make([]byte, config.BytesPerFrame())...)
+
+	dec := NewDecoder(config)
+	if done, err := dec.AddFrame(enc.frameDots(frame)); done || err != nil {
+		t.Fatalf("bogus first symbol: (%v, %v), want held (false, nil)", done, err)
+	}
+	if dec.fountain != nil {
+		t.Fatalf("LT decoder built for K=%d from one unchecked frame", dec.fountain.k)
+	}
+	for seed := 1; seed < 10*f.K(); seed++ {
+		if done, _ := dec.AddFrame(f.Symbol(seed).Dots); done {
+			break
+		}
+	}
+	if got, err := dec.Data(); err != nil || !bytes.HasPrefix(got, data) {
+		t.Fatalf("Data() = %q, %v; want prefix %q", got, err, data)
+	}
+}
+
+func TestLTNeighborsDistinct(t *testing.T) {
+	for _, k := range []int{1, 2, 7, 50, 255} {
+		cdf := robustSolitonCDF(k)
+		for seed := 0; seed < 500; seed++ {
+			nbrs := ltNeighbors(seed, cdf)
+			if len(nbrs) == 0 || len(nbrs) > k {
+				t.Fatalf("k=%d seed=%d: degree %d out of range", k, seed, len(nbrs))
+			}
+			seen := make(map[int]bool)
+			for _, n := range nbrs {
+				if n < 0 || n >= k || seen[n] {
+					t.Fatalf("k=%d seed=%d: bad neighbour set %v", k, seed, nbrs)
+				}
+				seen[n] = true
+			}
+		}
+	}
+}
+
+func TestLTNeighborsDeterministic(t *testing.T) {
+	cdf := robustSolitonCDF(40)
+	a := ltNeighbors(1234, cdf)
+	b := ltNeighbors(1234, cdf)
+	if len(a) != len(b) {
+		t.Fatalf("same seed produced different degrees: %d vs %d", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("same seed produced different neighbours: %v vs %v", a, b)
+		}
+	}
+}
+
+func TestRobustSolitonCDF(t *testing.T) {
+	cdf := robustSolitonCDF(100)
+	if len(cdf) != 100 {
+		t.Fatalf("expected 100 entries, got %d", len(cdf))
+	}
+	for i := 1; i < len(cdf); i++ {
+		if cdf[i] < cdf[i-1] {
+			t.Fatalf("cdf not monotonic at %d", i)
+		}
+	}
+	if math.Abs(cdf[99]-1) > 1e-9 {
+		t.Errorf("cdf does not end at 1: %f", cdf[99])
+	}
+}
@@ -0,0 +1,24 @@
+package dotbeam
+
+// Gray-coded symbol mapping.
+//
+// DefaultColors is ordered by hue, so a camera that misreads a dot almost
+// always picks a hue neighbour. In plain binary, neighbours such as Green
+// (011) and Cyan (100) can differ in every bit. With Config.GrayCode the
+// color at palette index i carries the bits toGray(i) instead, so every
+// pair of neighbours, including Magenta and Red at the wrap-around,
+// differs in exactly one bit.
+
+// toGray returns the reflected binary Gray code of v.
+func toGray(v uint8) uint8 {
+	return v ^ v>>1
+}
+
+// fromGray inverts toGray.
+func fromGray(g uint8) uint8 {
+	v := g
+	for s := g >> 1; s != 0; s >>= 1 {
+		v ^= s
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package dotbeam
+
+import (
+	"bytes"
+	"math/bits"
+	"testing"
+)
+
+func TestGrayCodeInverse(t *testing.T) {
+	for v := range 256 {
+		if got := fromGray(toGray(uint8(v))); got != uint8(v) {
+			t.Fatalf("fromGray(toGray(%d)) = %d", v, got)
+		}
+	}
+}
+
+func TestGrayCodeNeighbours(t *testing.T) {
+	// Palette indices adjacent in hue, including the Magenta → Red wrap,
+	// must decode to bit groups one bit apart.
+	n := len(DefaultColors)
+	for i := range n {
+		a, b := toGray(uint8(i)), toGray(uint8((i+1)%n))
+		if d := bits.OnesCount8(a ^ b); d != 1 {
+			t.Errorf("colors %d and %d differ in %d bits, want 1", i, (i+1)%n, d)
+		}
+	}
+}
+
+func TestGrayCodeRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.GrayCode = true
+
+	data := bytes.Repeat([]byte("gray "), 12)
+	if got := roundTrip(t, config, data); !bytes.HasPrefix(got, data) {
+		t.Fatalf("round-trip failed: got %q, want prefix %q", got, data)
+	}
+
+	// A receiver expecting plain binary rejects Gray-coded frames.
+	frames := mustEncode(t, NewEncoder(config), data)
+	plain := DefaultConfig()
+	plain.IndexBytes = 1
+	if _, err := NewDecoder(plain).AddFrame(frames[0].Dots); err != ErrInvalidFrame {
+		t.Fatalf("plain decoder: err = %v, want ErrInvalidFrame", err)
+	}
+}
+
+func TestGrayCodeHueMisreadFlipsOneBit(t *testing.T) {
+	config := DefaultConfig()
+	config.GrayCode = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	f := mustEncode(t, enc, []byte("neighbour"))[0]
+	want := dec.dotsToBytes(f.Dots)
+	for i := range f.Dots {
+		dots := append([]Dot(nil), f.Dots...)
+		dots[i].Value = (dots[i].Value + 1) % uint8(len(DefaultColors))
+
+		flipped := 0
+		for j, b := range dec.dotsToBytes(dots) {
+			flipped += bits.OnesCount8(b ^ want[j])
+		}
+		// The last dot's trailing bits fall outside the byte stream.
+		if flipped > 1 || flipped == 0 && (i+1)*config.BitsPerDot <= len(want)*8 {
+			t.Fatalf("misreading dot %d flipped %d bits, want 1", i, flipped)
+		}
+	}
+}
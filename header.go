@@ -0,0 +1,139 @@
+package dotbeam
+
+import "math/rand/v2"
+
+// Frame headers.
+//
+// The legacy header is [index, total], one byte each ([seed_hi, seed_lo, K]
+// for fountain frames), which caps a transfer at 255 frames. With
+// Config.IndexBytes, Config.Session or Config.GrayCode set, frames carry a
+// versioned header instead:
+//
+//	[version<<4 | flags][session × 2]?[index × w][total × w]
+//
+// where w = max(IndexBytes, 1) and the fields are big-endian. The session
+// ID is present only when flagSession is set. flagGray records that dot
+// values are Gray-coded; a receiver configured otherwise rejects the frame.
+// Fountain frames use max(2, w) bytes for the seed so the stream never
+// wraps early.
+
+// headerVersion is the version nibble of the versioned header.
+const headerVersion = 1
+
+// Versioned header flags (low nibble of the first byte).
+const (
+	flagWidthMask = 0x03 // index/total field width in bytes (1-3)
+	flagSession   = 0x04 // a 2-byte session ID follows the first byte
+	flagGray      = 0x08 // dot values are Gray-coded
+)
+
+// sessionSize is the size of the session ID field.
+const sessionSize = 2
+
+// frameHeader holds the decoded fields of a frame header. For fountain
+// frames index is the seed and total is K.
+type frameHeader struct {
+	index   int
+	total   int
+	session uint16
+}
+
+// versioned reports whether frames carry the versioned header.
+func (c Config) versioned() bool {
+	return c.IndexBytes > 0 || c.Session || c.GrayCode
+}
+
+// flags returns the low nibble of the versioned header byte.
+func (c Config) flags() byte {
+	f := byte(c.totalWidth()) & flagWidthMask
+	if c.Session {
+		f |= flagSession
+	}
+	if c.GrayCode {
+		f |= flagGray
+	}
+	return f
+}
+
+// indexWidth returns the size in bytes of the frame index (or seed) field.
+func (c Config) indexWidth() int {
+	w := max(c.IndexBytes, 1)
+	if c.UseFountain {
+		w = max(w, 2)
+	}
+	return w
+}
+
+// totalWidth returns the size in bytes of the frame total (or K) field.
+func (c Config) totalWidth() int {
+	return max(c.IndexBytes, 1)
+}
+
+// MaxFrames returns the largest frame total (source block count for
+// fountain coding) the configured header can express.
+func (c Config) MaxFrames() int {
+	return 1<<(8*c.totalWidth()) - 1
+}
+
+// maxSeed returns the largest fountain seed the header can carry.
+func (c Config) maxSeed() int {
+	return 1<<(8*c.indexWidth()) - 1
+}
+
+// putHeader builds the frame header.
+func (c Config) putHeader(h frameHeader) []byte {
+	var buf []byte
+	if c.versioned() {
+		buf = append(buf, headerVersion<<4|c.flags())
+	}
+	if c.Session {
+		buf = appendUint(buf, int(h.session), sessionSize)
+	}
+	buf = appendUint(buf, h.index, c.indexWidth())
+	return appendUint(buf, h.total, c.totalWidth())
+}
+
+// parseHeader splits frame bytes into header and payload. It returns
+// ErrInvalidFrame if the header is truncated or does not match the config.
+func (c Config) parseHeader(data []byte) (frameHeader, []byte, error) {
+	var h frameHeader
+	if len(data) < c.headerSize() {
+		return h, nil, ErrInvalidFrame
+	}
+	if c.versioned() {
+		if data[0]>>4 != headerVersion || data[0]&0x0F != c.flags() {
+			return h, nil, ErrInvalidFrame
+		}
+		data = data[1:]
+	}
+	if c.Session {
+		h.session = uint16(readUint(data[:sessionSize]))
+		data = data[sessionSize:]
+	}
+	iw, tw := c.indexWidth(), c.totalWidth()
+	h.index = readUint(data[:iw])
+	h.total = readUint(data[iw : iw+tw])
+	return h, data[iw+tw:], nil
+}
+
+// newSessionID returns a random session ID for a new transfer.
+func newSessionID() uint16 {
+	return uint16(rand.Uint32())
+}
+
+// appendUint appends v as a big-endian integer of width bytes.
+func appendUint(buf []byte, v, width int) []byte {
+	for i := width - 1; i >= 0; i-- {
+		buf = append(buf, byte(v>>(8*i)))
+	}
+	return buf
+}
+
+// readUint reads a big-endian integer.
+func readUint(b []byte) int {
+	v := 0
+	for _, c := range b {
+		v = v<<8 | int(c)
+	}
+	return v
+}
@@ -0,0 +1,286 @@
+package dotbeam
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestEncodeTooLarge(t *testing.T) {
+	config := DefaultConfig()
+	enc := NewEncoder(config)
+
+	// 255 frames × 20 bytes is the legacy limit; one more byte must fail
+	// instead of being silently dropped.
+	limit := config.MaxFrames() * config.BytesPerFrame()
+	if _, err := enc.Encode(make([]byte, limit)); err != nil {
+		t.Fatalf("Encode(%d bytes) error: %v", limit, err)
+	}
+	if _, err := enc.Encode(make([]byte, limit+1)); err != ErrTooLarge {
+		t.Errorf("Encode(%d bytes) = %v, want ErrTooLarge", limit+1, err)
+	}
+
+	config.UseFountain = true
+	big := make([]byte, config.MaxFrames()*config.BytesPerFrame()+1)
+	if _, err := NewEncoder(config).Fountain(big); err != ErrTooLarge {
+		t.Errorf("Fountain(%d bytes) = %v, want ErrTooLarge", len(big), err)
+	}
+}
+
+func TestEncodeInvalidConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.IndexBytes = 4
+	if _, err := NewEncoder(config).Encode([]byte("x")); err != ErrInvalidConfig {
+		t.Errorf("IndexBytes=4: err = %v, want ErrInvalidConfig", err)
+	}
+
+	config = DefaultConfig()
+	config.Rings = 1 // 18 bits: no room for a header
+	if _, err := NewEncoder(config).Encode([]byte("x")); err != ErrInvalidConfig {
+		t.Errorf("Rings=1: err = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestWideHeaderSizes(t *testing.T) {
+	tests := []struct {
+		indexBytes, fountain, header, maxFrames int
+	}{
+		{0, 0, 2, 255},
+		{0, 1, 3, 255},
+		{1, 0, 3, 255},
+		{2, 0, 5, 65535},
+		{2, 1, 5, 65535},
+		{3, 0, 7, 1<<24 - 1},
+		{3, 1, 7, 1<<24 - 1},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.IndexBytes = tt.indexBytes
+		config.UseFountain = tt.fountain == 1
+		if got := config.headerSize(); got != tt.header {
+			t.Errorf("IndexBytes=%d fountain=%v: headerSize = %d, want %d",
+				tt.indexBytes, config.UseFountain, got, tt.header)
+		}
+		if got := config.MaxFrames(); got != tt.maxFrames {
+			t.Errorf("IndexBytes=%d: MaxFrames = %d, want %d", tt.indexBytes, got, tt.maxFrames)
+		}
+	}
+}
+
+func TestWideHeaderRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.IndexBytes = 2
+	config.Preamble = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	// Well past the legacy 5,100-byte limit.
+	data := make([]byte, 8000)
+	rand.New(rand.NewSource(8)).Read(data)
+	frames := mustEncode(t, enc, data)
+	if len(frames) <= 255 {
+		t.Fatalf("expected more than 255 frames, got %d", len(frames))
+	}
+
+	// Feed in reverse to exercise indices above 255 first.
+	for i := len(frames) - 1; i >= 0; i-- {
+		if _, err := dec.AddFrame(frames[i].Dots); err != nil {
+			t.Fatalf("AddFrame(%d) error: %v", i, err)
+		}
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("round-trip mismatch")
+	}
+}
+
+func TestWideHeaderFountain(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	config.IndexBytes = 3
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("wide fountain "), 40)
+	f := mustFountain(t, enc, data)
+
+	// Seeds beyond 16 bits only exist with the 3-byte header.
+	for seed := 70000; seed < 72000; seed++ {
+		frame := f.Symbol(seed)
+		if frame.Index != seed {
+			t.Fatalf("Symbol(%d).Index = %d", seed, frame.Index)
+		}
+		if done, err := dec.AddFrame(frame.Dots); err != nil {
+			t.Fatalf("AddFrame(seed %d) error: %v", seed, err)
+		} else if done {
+			break
+		}
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatal("round-trip mismatch")
+	}
+}
+
+func TestWideHeaderMismatch(t *testing.T) {
+	config := DefaultConfig()
+	config.IndexBytes = 2
+	frames := mustEncode(t, NewEncoder(config), []byte("two-byte fields"))
+
+	config.IndexBytes = 1
+	dec := NewDecoder(config)
+	if _, err := dec.AddFrame(frames[0].Dots); err != ErrInvalidFrame {
+		t.Errorf("AddFrame with wrong width = %v, want ErrInvalidFrame", err)
+	}
+}
+
+func TestSessionIgnoresForeignFrames(t *testing.T) {
+	config := DefaultConfig()
+	config.Session = true
+	config.Checksum = true // trust the first frame's session
+	encA := NewEncoder(config)
+	encB := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	msgA := bytes.Repeat([]byte("A"), 45)
+	msgB := bytes.Repeat([]byte("B"), 45)
+	framesA := mustEncode(t, encA, msgA)
+	framesB := mustEncode(t, encB, msgB)
+	for encA.SessionID() == encB.SessionID() {
+		framesB = mustEncode(t, encB, msgB)
+	}
+
+	if _, ok := dec.SessionID(); ok {
+		t.Fatal("SessionID reported before any frame")
+	}
+
+	// Two screens in view: frames from both transfers interleave.
+	for i := range framesA {
+		if _, err := dec.AddFrame(framesA[i].Dots); err != nil {
+			t.Fatalf("AddFrame(A%d) error: %v", i, err)
+		}
+		if _, err := dec.AddFrame(framesB[i].Dots); err != ErrSessionMismatch {
+			t.Fatalf("AddFrame(B%d) = %v, want ErrSessionMismatch", i, err)
+		}
+	}
+
+	id, ok := dec.SessionID()
+	if !ok || id != encA.SessionID() {
+		t.Errorf("SessionID() = (%#04x, %v), want (%#04x, true)", id, ok, encA.SessionID())
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, msgA) {
+		t.Fatalf("decoded %q, want prefix %q", got, msgA)
+	}
+
+	// After Reset the decoder follows the other transfer.
+	dec.Reset()
+	for _, f := range framesB {
+		if _, err := dec.AddFrame(f.Dots); err != nil {
+			t.Fatalf("AddFrame after Reset error: %v", err)
+		}
+	}
+	if id, _ := dec.SessionID(); id != encB.SessionID() {
+		t.Errorf("SessionID() after Reset = %#04x, want %#04x", id, encB.SessionID())
+	}
+}
+
+func TestSessionMisreadFirstFrame(t *testing.T) {
+	config := DefaultConfig()
+	config.Session = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("misread session "), 4)
+	frames := mustEncode(t, enc, data)
+	if len(frames) < 3 {
+		t.Fatalf("want at least 3 frames, got %d", len(frames))
+	}
+
+	// Dot 4 carries session bits: the first read claims another session.
+	bad := misread(frames[0].Dots, 4)
+	if done, err := dec.AddFrame(bad); done || err != nil {
+		t.Fatalf("misread first frame: (%v, %v), want held (false, nil)", done, err)
+	}
+	if _, ok := dec.SessionID(); ok {
+		t.Fatal("session locked on a single unchecked frame")
+	}
+
+	for i := 1; i < len(frames); i++ {
+		if _, err := dec.AddFrame(frames[i].Dots); err != nil {
+			t.Fatalf("AddFrame(%d) error: %v", i, err)
+		}
+	}
+	if id, ok := dec.SessionID(); !ok || id != enc.SessionID() {
+		t.Fatalf("SessionID() = (%#04x, %v), want (%#04x, true)", id, ok, enc.SessionID())
+	}
+	if _, err := dec.AddFrame(bad); err != ErrSessionMismatch {
+		t.Fatalf("misread frame after lock: err = %v, want ErrSessionMismatch", err)
+	}
+
+	// The carousel comes round again and frame 0 completes the transfer.
+	done, err := dec.AddFrame(frames[0].Dots)
+	if err != nil || !done {
+		t.Fatalf("AddFrame(0) = (%v, %v), want (true, nil)", done, err)
+	}
+	got, err := dec.Data()
+	if err != nil || !bytes.HasPrefix(got, data) {
+		t.Fatalf("Data() = %q, %v; want prefix %q", got, err, data)
+	}
+}
+
+func TestSessionNewPerTransfer(t *testing.T) {
+	config := DefaultConfig()
+	config.Session = true
+	enc := NewEncoder(config)
+	seen := make(map[uint16]bool)
+	for i := 0; i < 8; i++ {
+		mustEncode(t, enc, []byte("same message"))
+		seen[enc.SessionID()] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("8 transfers produced %d distinct session IDs", len(seen))
+	}
+}
+
+func TestSessionFountain(t *testing.T) {
+	config := DefaultConfig()
+	config.Session = true
+	config.UseFountain = true
+	config.Checksum = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("session fountain "), 10)
+	f := mustFountain(t, enc, data)
+	stale := mustFountain(t, NewEncoder(config), data)
+	for stale.session == f.session {
+		stale = mustFountain(t, NewEncoder(config), data)
+	}
+
+	dec.AddFrame(f.Symbol(0).Dots)
+	if _, err := dec.AddFrame(stale.Symbol(1).Dots); err != ErrSessionMismatch {
+		t.Fatalf("foreign symbol: err = %v, want ErrSessionMismatch", err)
+	}
+	for seed := 1; seed < 200; seed++ {
+		if done, _ := dec.AddFrame(f.Symbol(seed).Dots); done {
+			break
+		}
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatal("round-trip mismatch")
+	}
+}
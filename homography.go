@@ -0,0 +1,91 @@
+package dotbeam
+
+import "math"
+
+// Perspective correction.
+//
+// A phone held at an angle to the screen sees the rings as ellipses, which
+// rotation and uniform scale cannot describe. The three anchors and the
+// orientation cue give four known layout points; the homography through
+// them maps the whole layout plane onto the image, so DecodeImage samples
+// every dot where the tilted screen actually put it.
+
+// Homography is a 3×3 projective transform of the plane. A layout point
+// (x, y) maps to (h00·x + h01·y + h02, h10·x + h11·y + h12) divided by
+// h20·x + h21·y + h22.
+type Homography [3][3]float64
+
+// Apply maps a layout point through h.
+func (h Homography) Apply(x, y float64) (float64, float64) {
+	w := h[2][0]*x + h[2][1]*y + h[2][2]
+	return (h[0][0]*x + h[0][1]*y + h[0][2]) / w,
+		(h[1][0]*x + h[1][1]*y + h[1][2]) / w
+}
+
+// fitHomography returns the homography that maps each src point exactly
+// onto the matching dst point. ok is false if three of the points are
+// collinear.
+func fitHomography(src, dst [4]Anchor) (h Homography, ok bool) {
+	// With h22 fixed at 1, each correspondence gives two linear equations
+	// in the remaining eight entries.
+	a := make([][]float64, 8)
+	b := make([]float64, 8)
+	for i := range src {
+		x, y := src[i].X, src[i].Y
+		u, v := dst[i].X, dst[i].Y
+		a[2*i] = []float64{x, y, 1, 0, 0, 0, -u * x, -u * y}
+		b[2*i] = u
+		a[2*i+1] = []float64{0, 0, 0, x, y, 1, -v * x, -v * y}
+		b[2*i+1] = v
+	}
+	p, ok := solveLinear(a, b)
+	if !ok {
+		return h, false
+	}
+	return Homography{
+		{p[0], p[1], p[2]},
+		{p[3], p[4], p[5]},
+		{p[6], p[7], 1},
+	}, true
+}
+
+// solveLinear solves the square linear system a·x = b by Gaussian
+// elimination with partial pivoting, leaving a and b unchanged. ok is false
+// if a is singular.
+func solveLinear(a [][]float64, b []float64) (x []float64, ok bool) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = append(append(make([]float64, 0, n+1), a[i]...), b[i])
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-9 {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for r := col + 1; r < n; r++ {
+			f := m[r][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[r][k] -= f * m[col][k]
+			}
+		}
+	}
+
+	x = make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := m[r][n]
+		for k := r + 1; k < n; k++ {
+			sum -= m[r][k] * x[k]
+		}
+		x[r] = sum / m[r][r]
+	}
+	return x, true
+}
@@ -0,0 +1,40 @@
+package dotbeam
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitHomography(t *testing.T) {
+	want := Homography{
+		{250, -40, 300},
+		{30, 220, 310},
+		{0.15, -0.08, 1},
+	}
+	src := [4]Anchor{{X: 0, Y: 0.82}, {X: 0.71, Y: -0.41}, {X: -0.71, Y: -0.41}, {X: 0.46, Y: 0.8}}
+	var dst [4]Anchor
+	for i, p := range src {
+		dst[i].X, dst[i].Y = want.Apply(p.X, p.Y)
+	}
+
+	h, ok := fitHomography(src, dst)
+	if !ok {
+		t.Fatal("fitHomography failed")
+	}
+	// The fit must agree everywhere on the plane, not just at the four
+	// points it was given.
+	for _, p := range []Anchor{{X: 0, Y: 0}, {X: 0.7, Y: 0.3}, {X: -0.5, Y: 0.6}} {
+		gx, gy := h.Apply(p.X, p.Y)
+		wx, wy := want.Apply(p.X, p.Y)
+		if math.Hypot(gx-wx, gy-wy) > 1e-6 {
+			t.Errorf("Apply(%v, %v) = (%.3f, %.3f), want (%.3f, %.3f)", p.X, p.Y, gx, gy, wx, wy)
+		}
+	}
+}
+
+func TestFitHomographyCollinear(t *testing.T) {
+	src := [4]Anchor{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}}
+	if _, ok := fitHomography(src, src); ok {
+		t.Error("fitHomography(collinear) ok = true, want false")
+	}
+}
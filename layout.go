@@ -2,12 +2,24 @@ package dotbeam
 
 import "math"
 
+// anchorRadius is the normalized radius of the three anchor dots.
+const anchorRadius = 0.82
+
+// The orientation cue is a small white dot 30° clockwise of the 270°
+// anchor, outside the data rings. The anchor triangle alone looks the same
+// mirrored; the cue does not.
+const (
+	cueAngle  = 300
+	cueRadius = 0.92
+)
+
 // Layout holds the computed positions of all dots and anchors.
 type Layout struct {
 	Config  Config
 	Width   float64
 	Height  float64
 	Anchors [3]Anchor
+	Cue     Anchor // orientation cue, breaks the triangle's mirror symmetry
 	Rings   []RingLayout
 }
 
@@ -22,6 +34,7 @@ type RingLayout struct {
 type Position struct {
 	X, Y  float64
 	Angle float64
+	Pilot bool // reserved for a pilot dot (Config.Pilots)
 }
 
 // NewLayout computes dot positions for the given config and canvas size.
@@ -33,14 +46,15 @@ func NewLayout(config Config, width, height float64) Layout {
 	}
 
 	// Anchors form an equilateral triangle at radius 0.82
-	anchorRadius := 0.82
 	l.Anchors = [3]Anchor{
 		angleToPoint(270, anchorRadius), // Top
 		angleToPoint(30, anchorRadius),  // Bottom-right
 		angleToPoint(150, anchorRadius), // Bottom-left
 	}
+	l.Cue = angleToPoint(cueAngle, cueRadius)
 
-	// Data rings
+	// Data rings; the first positions are pilots if enabled
+	pilots := config.pilotCount()
 	l.Rings = make([]RingLayout, config.Rings)
 	for i := 0; i < config.Rings; i++ {
 		ring := i + 1
@@ -51,7 +65,8 @@ func NewLayout(config Config, width, height float64) Layout {
 		for j := 0; j < dotCount; j++ {
 			angle := float64(j) * 360.0 / float64(dotCount)
 			p := angleToPoint(angle, radius)
-			positions[j] = Position{X: p.X, Y: p.Y, Angle: angle}
+			positions[j] = Position{X: p.X, Y: p.Y, Angle: angle, Pilot: pilots > 0}
+			pilots--
 		}
 
 		l.Rings[i] = RingLayout{
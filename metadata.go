@@ -0,0 +1,192 @@
+package dotbeam
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// preambleVersion is the current preamble format version.
+const preambleVersion = 1
+
+// Preamble flags.
+const (
+	flagEncrypted  = 0x01 // payload is sealed with AES-GCM
+	flagSigned     = 0x02 // an Ed25519 signature follows the payload
+	flagCompressed = 0x04 // payload is raw DEFLATE
+
+	knownFlags = flagEncrypted | flagSigned | flagCompressed
+)
+
+// Preamble field tags. Tag 0 terminates the field list.
+const (
+	tagEnd         = 0
+	tagContentType = 1
+	tagFilename    = 2
+	tagSalt        = 3 // PBKDF2 salt for passphrase-derived keys
+	tagNonce       = 4 // AES-GCM nonce
+	tagSigner      = 5 // fingerprint of the signing key
+	tagOriginal    = 6 // uvarint payload length before compression and encryption
+)
+
+// Metadata describes a transfer. With Config.Preamble set it travels
+// in-band ahead of the payload, so the receiver learns the exact length
+// without any side channel.
+type Metadata struct {
+	Length         int    // Payload bytes on the wire, after compression and encryption (set by the encoder)
+	OriginalLength int    // Payload length before compression and encryption (set by the encoder)
+	ContentType    string // Optional MIME type, e.g. "text/plain"
+	Filename       string // Optional original file name
+	Signer         string // Fingerprint of the signing key, if signed (set by the encoder)
+}
+
+// preamble is a decoded preamble: the public metadata plus the parameters
+// needed to unwrap the payload.
+type preamble struct {
+	Metadata
+	flags  byte
+	salt   []byte
+	nonce  []byte
+	signer []byte
+}
+
+// SetMetadata sets the content type and filename sent with subsequent
+// transfers. It has no effect unless Config.Preamble is set.
+func (e *Encoder) SetMetadata(meta Metadata) {
+	e.meta = meta
+}
+
+// transferBytes returns the byte stream that is actually split into frames:
+// the payload itself, or preamble + payload when Config.Preamble is set.
+// The payload is compressed, encrypted and signed in that order as
+// configured. These steps require the preamble; without it ErrInvalidConfig
+// is returned.
+func (e *Encoder) transferBytes(data []byte) ([]byte, error) {
+	if !e.config.Preamble {
+		if e.secret.enabled() || e.signKey != nil {
+			return nil, ErrInvalidConfig
+		}
+		return data, nil
+	}
+
+	p := preamble{Metadata: e.meta}
+	p.OriginalLength = len(data)
+	if e.config.Compress {
+		data = compress(&p, data)
+	}
+	p.Length = len(data)
+	if e.signKey != nil {
+		e.prepareSignature(&p)
+	}
+	if e.secret.enabled() {
+		var err error
+		if data, err = e.seal(&p, data); err != nil {
+			return nil, err
+		}
+	}
+	stream := append(marshalPreamble(p), data...)
+	if e.signKey != nil {
+		stream = e.appendSignature(stream)
+	}
+	return stream, nil
+}
+
+// marshalPreamble serializes a preamble as:
+//
+//	[version][flags][uvarint length]{[tag][uvarint len][value]}...[0]
+//
+// The original length is only sent when it differs from Length.
+func marshalPreamble(p preamble) []byte {
+	buf := []byte{preambleVersion, p.flags}
+	buf = binary.AppendUvarint(buf, uint64(p.Length))
+	if p.OriginalLength != p.Length {
+		buf = appendField(buf, tagOriginal, binary.AppendUvarint(nil, uint64(p.OriginalLength)))
+	}
+	buf = appendField(buf, tagContentType, []byte(p.ContentType))
+	buf = appendField(buf, tagFilename, []byte(p.Filename))
+	buf = appendField(buf, tagSalt, p.salt)
+	buf = appendField(buf, tagNonce, p.nonce)
+	buf = appendField(buf, tagSigner, p.signer)
+	return append(buf, tagEnd)
+}
+
+// appendField appends a tagged field, skipping empty values.
+func appendField(buf []byte, tag byte, value []byte) []byte {
+	if len(value) == 0 {
+		return buf
+	}
+	buf = append(buf, tag)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+// parsePreamble decodes the preamble at the start of stream and returns it
+// with its length. It returns ErrIncompleteData if stream ends before the
+// preamble does.
+func parsePreamble(stream []byte) (preamble, int, error) {
+	var p preamble
+	if len(stream) < 2 {
+		return p, 0, ErrIncompleteData
+	}
+	if stream[0] != preambleVersion || stream[1]&^knownFlags != 0 {
+		return p, 0, ErrInvalidMetadata
+	}
+	p.flags = stream[1]
+	pos := 2
+
+	length, n := binary.Uvarint(stream[pos:])
+	if n == 0 {
+		return p, 0, ErrIncompleteData
+	}
+	if n < 0 || length > 1<<40 {
+		return p, 0, ErrInvalidMetadata
+	}
+	p.Length = int(length)
+	p.OriginalLength = p.Length
+	pos += n
+
+	for {
+		if pos >= len(stream) {
+			return p, 0, ErrIncompleteData
+		}
+		tag := stream[pos]
+		pos++
+		if tag == tagEnd {
+			return p, pos, nil
+		}
+
+		size, n := binary.Uvarint(stream[pos:])
+		if n == 0 {
+			return p, 0, ErrIncompleteData
+		}
+		if n < 0 {
+			return p, 0, ErrInvalidMetadata
+		}
+		pos += n
+		if uint64(len(stream)-pos) < size {
+			return p, 0, ErrIncompleteData
+		}
+		value := stream[pos : pos+int(size)]
+		pos += int(size)
+
+		switch tag {
+		case tagContentType:
+			p.ContentType = string(value)
+		case tagFilename:
+			p.Filename = string(value)
+		case tagSalt:
+			p.salt = value
+		case tagNonce:
+			p.nonce = value
+		case tagSigner:
+			p.signer = value
+			p.Signer = hex.EncodeToString(value)
+		case tagOriginal:
+			original, n := binary.Uvarint(value)
+			if n != len(value) || original > 1<<40 {
+				return p, 0, ErrInvalidMetadata
+			}
+			p.OriginalLength = int(original)
+		}
+		// Unknown tags are skipped for forward compatibility.
+	}
+}
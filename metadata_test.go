@@ -0,0 +1,153 @@
+package dotbeam
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPreambleRoundTripTrailingZeros(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+
+	// Binary payload that genuinely ends in zero bytes.
+	data := append([]byte{0xde, 0xad, 0xbe, 0xef}, make([]byte, 9)...)
+	if got := roundTrip(t, config, data); !bytes.Equal(got, data) {
+		t.Fatalf("round-trip failed:\n got: %x\nwant: %x", got, data)
+	}
+}
+
+func TestPreambleMetadata(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.SetMetadata(Metadata{ContentType: "text/vcard", Filename: "alice.vcf"})
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("BEGIN:VCARD "), 8)
+	frames := mustEncode(t, enc, data)
+	if len(frames) < 2 {
+		t.Fatalf("expected multiple frames, got %d", len(frames))
+	}
+
+	// The preamble spans the first two frames, so metadata is known
+	// before the payload is complete.
+	if _, err := dec.Metadata(); err != ErrIncompleteData {
+		t.Errorf("Metadata() before any frame = %v, want ErrIncompleteData", err)
+	}
+	dec.AddFrame(frames[0].Dots)
+	if _, err := dec.Metadata(); err != ErrIncompleteData {
+		t.Errorf("Metadata() after frame 0 = %v, want ErrIncompleteData", err)
+	}
+	dec.AddFrame(frames[1].Dots)
+	meta, err := dec.Metadata()
+	if err != nil {
+		t.Fatalf("Metadata() error: %v", err)
+	}
+	want := Metadata{Length: len(data), OriginalLength: len(data), ContentType: "text/vcard", Filename: "alice.vcf"}
+	if meta != want {
+		t.Errorf("Metadata() = %+v, want %+v", meta, want)
+	}
+
+	for _, f := range frames[2:] {
+		dec.AddFrame(f.Dots)
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("round-trip failed:\n got: %q\nwant: %q", got, data)
+	}
+}
+
+func TestPreambleFountain(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	config.UseFountain = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := []byte("fountain with exact length\x00\x00")
+	for _, f := range mustEncode(t, enc, data) {
+		if done, _ := dec.AddFrame(f.Dots); done {
+			break
+		}
+	}
+
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("round-trip failed:\n got: %q\nwant: %q", got, data)
+	}
+}
+
+func TestPreambleEmptyPayload(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	frames := mustEncode(t, enc, nil)
+	if len(frames) != 1 {
+		t.Fatalf("expected 1 frame carrying only the preamble, got %d", len(frames))
+	}
+	dec.AddFrame(frames[0].Dots)
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected empty payload, got %q", got)
+	}
+}
+
+func TestParsePreamble(t *testing.T) {
+	meta := Metadata{Length: 300, OriginalLength: 1200, ContentType: "application/json", Filename: "config.json"}
+	buf := marshalPreamble(preamble{Metadata: meta})
+
+	got, n, err := parsePreamble(append(buf, "trailing"...))
+	if err != nil {
+		t.Fatalf("parsePreamble error: %v", err)
+	}
+	if n != len(buf) {
+		t.Errorf("preamble length = %d, want %d", n, len(buf))
+	}
+	if got.Metadata != meta {
+		t.Errorf("parsePreamble = %+v, want %+v", got, meta)
+	}
+
+	// Without the original-length field it defaults to Length.
+	plain := marshalPreamble(preamble{Metadata: Metadata{Length: 300, OriginalLength: 300}})
+	if got, _, err := parsePreamble(plain); err != nil || got.OriginalLength != 300 {
+		t.Errorf("OriginalLength = %d, %v; want 300", got.OriginalLength, err)
+	}
+
+	// Every strict prefix is incomplete, never invalid.
+	for i := 0; i < len(buf); i++ {
+		if _, _, err := parsePreamble(buf[:i]); err != ErrIncompleteData {
+			t.Fatalf("prefix %d: err = %v, want ErrIncompleteData", i, err)
+		}
+	}
+
+	if _, _, err := parsePreamble([]byte{99, 0, 0, 0}); err != ErrInvalidMetadata {
+		t.Errorf("bad version: err = %v, want ErrInvalidMetadata", err)
+	}
+}
+
+func TestPreambleLengthMismatch(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	dec := NewDecoder(config)
+
+	// Claim 200 bytes in a single-frame transfer.
+	enc := NewEncoder(DefaultConfig())
+	bogus := marshalPreamble(preamble{Metadata: Metadata{Length: 200}})
+	for _, f := range mustEncode(t, enc, bogus) {
+		dec.AddFrame(f.Dots)
+	}
+	if _, err := dec.Data(); err != ErrInvalidMetadata {
+		t.Errorf("Data() = %v, want ErrInvalidMetadata", err)
+	}
+}
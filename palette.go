@@ -0,0 +1,126 @@
+package dotbeam
+
+// Palette maps dot values to colors: entry i is the color of value i. A
+// palette for BitsPerDot bits has exactly 1 << BitsPerDot colors. The
+// built-in palettes are fully saturated hues ordered around the color
+// wheel, so the scanner's hue matcher can tell them apart and Gray coding
+// (Config.GrayCode) makes neighbouring hues differ by one bit.
+type Palette []Color
+
+// Built-in palettes for 1, 2, 3 and 4 bits per dot.
+var (
+	// Palette2 (1 bit per dot): two hues far apart, one bright and one
+	// deep, for poor cameras and bright rooms.
+	Palette2 = Palette{
+		{R: 0xFF, G: 0xD7, B: 0x00}, // 0: Gold
+		{R: 0x44, G: 0x88, B: 0xFF}, // 1: Blue
+	}
+
+	// Palette4 (2 bits per dot): four hues roughly 90° apart.
+	Palette4 = Palette{
+		{R: 0xFF, G: 0x8C, B: 0x00}, // 00: Orange
+		{R: 0x44, G: 0xFF, B: 0x44}, // 01: Green
+		{R: 0x44, G: 0x88, B: 0xFF}, // 10: Blue
+		{R: 0xFF, G: 0x44, B: 0xFF}, // 11: Magenta
+	}
+
+	// Palette8 (3 bits per dot) is DefaultColors.
+	Palette8 = Palette(DefaultColors[:])
+
+	// PaletteCVD (3 bits per dot) is an alternative to DefaultColors for
+	// viewers and cameras with poor red-green (or blue-yellow) contrast.
+	// It trades some saturation for lightness differences: every pair of
+	// colors, and each color against the background, stays at least 19
+	// CIEDE2000 ΔE apart under simulated protanopia, deuteranopia and
+	// tritanopia (DefaultColors drops below 3 for deuteranopia). Hues stay
+	// distinct and in order for the hue matcher and Gray coding.
+	PaletteCVD = Palette{
+		{R: 0xB0, G: 0x02, B: 0x00}, // 000: Dark red
+		{R: 0xD6, G: 0xA0, B: 0x4F}, // 001: Ochre
+		{R: 0xFF, G: 0xFF, B: 0x00}, // 010: Yellow
+		{R: 0x59, G: 0xFD, B: 0xC4}, // 011: Mint
+		{R: 0x45, G: 0xA8, B: 0xB0}, // 100: Teal
+		{R: 0x12, G: 0x0B, B: 0xB2}, // 101: Ultramarine
+		{R: 0xA0, G: 0x40, B: 0xFF}, // 110: Violet
+		{R: 0xEC, G: 0x36, B: 0x7F}, // 111: Rose
+	}
+
+	// Palette16 (4 bits per dot): sixteen hues 22.5° apart, for
+	// short-distance transfers such as desktop to webcam.
+	Palette16 = Palette{
+		{R: 0xFF, G: 0x33, B: 0x33}, // 0000: Red
+		{R: 0xFF, G: 0x80, B: 0x33}, // 0001: Orange
+		{R: 0xFF, G: 0xCC, B: 0x33}, // 0010: Amber
+		{R: 0xE6, G: 0xFF, B: 0x33}, // 0011: Yellow
+		{R: 0x99, G: 0xFF, B: 0x33}, // 0100: Chartreuse
+		{R: 0x4C, G: 0xFF, B: 0x33}, // 0101: Lime
+		{R: 0x33, G: 0xFF, B: 0x66}, // 0110: Green
+		{R: 0x33, G: 0xFF, B: 0xB2}, // 0111: Spring
+		{R: 0x33, G: 0xFF, B: 0xFF}, // 1000: Cyan
+		{R: 0x33, G: 0xB2, B: 0xFF}, // 1001: Azure
+		{R: 0x33, G: 0x66, B: 0xFF}, // 1010: Blue
+		{R: 0x4C, G: 0x33, B: 0xFF}, // 1011: Indigo
+		{R: 0x99, G: 0x33, B: 0xFF}, // 1100: Violet
+		{R: 0xE6, G: 0x33, B: 0xFF}, // 1101: Purple
+		{R: 0xFF, G: 0x33, B: 0xCC}, // 1110: Magenta
+		{R: 0xFF, G: 0x33, B: 0x80}, // 1111: Rose
+	}
+)
+
+// DefaultPalette returns the built-in palette for bitsPerDot (1 to 4), or
+// nil if there is none.
+func DefaultPalette(bitsPerDot int) Palette {
+	switch bitsPerDot {
+	case 1:
+		return Palette2
+	case 2:
+		return Palette4
+	case 3:
+		return Palette8
+	case 4:
+		return Palette16
+	}
+	return nil
+}
+
+// Colors returns the palette frames are drawn and read with: Config.Palette
+// if set, otherwise the built-in palette for BitsPerDot.
+func (c Config) Colors() Palette {
+	if c.Palette != nil {
+		return c.Palette
+	}
+	return DefaultPalette(c.BitsPerDot)
+}
+
+// minHueGap is the smallest hue difference, in degrees, allowed between
+// two chromatic palette colors.
+const minHueGap = 1.0
+
+// distinctHues reports whether every pair of chromatic colors in p differs
+// in hue. The scanner matches saturated samples by hue alone, so it cannot
+// tell apart two shades of one hue, such as a bright and a dark red.
+func (p Palette) distinctHues() bool {
+	var hues []float64
+	for _, c := range p {
+		h := hue(colorRGB(c))
+		if h < 0 {
+			continue // achromatic colors are matched by RGB distance
+		}
+		for _, other := range hues {
+			if hueDist(h, other) < minHueGap {
+				return false
+			}
+		}
+		hues = append(hues, h)
+	}
+	return true
+}
+
+// color returns the color of dot value v, wrapping out-of-range values.
+// An empty palette draws every dot black.
+func (p Palette) color(v uint8) Color {
+	if len(p) == 0 {
+		return Color{}
+	}
+	return p[int(v)%len(p)]
+}
@@ -0,0 +1,74 @@
+package dotbeam
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestDefaultPaletteSizes(t *testing.T) {
+	for bits := 1; bits <= 4; bits++ {
+		if got := len(DefaultPalette(bits)); got != 1<<bits {
+			t.Errorf("DefaultPalette(%d) has %d colors, want %d", bits, got, 1<<bits)
+		}
+	}
+	if DefaultPalette(5) != nil {
+		t.Error("DefaultPalette(5) should be nil")
+	}
+}
+
+func TestPaletteBitsPerDot(t *testing.T) {
+	msg := []byte("palettes for every density")
+	for _, bits := range []int{1, 2, 3, 4} {
+		cfg := DefaultConfig()
+		cfg.BitsPerDot = bits
+		cfg.Checksum = true
+
+		got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image { return img })
+		if !bytes.HasPrefix(got, msg) {
+			t.Fatalf("%d bits/dot: got %q, want prefix %q", bits, got, msg)
+		}
+	}
+}
+
+func TestCustomPalette(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BitsPerDot = 2
+	cfg.Palette = Palette{Palette8[0], Palette8[2], Palette8[4], Palette8[6]}
+
+	frames := mustEncode(t, NewEncoder(cfg), []byte("custom"))
+	img := RenderFrame(frames[0], NewLayout(cfg, 1, 1), 600, 600)
+	dot := frames[0].Dots[0]
+	px, py := ScaleToCanvas(dot.X, dot.Y, 600, 600)
+	want := cfg.Palette[dot.Value]
+	if c := img.RGBAAt(int(px), int(py)); c.R != want.R || c.G != want.G || c.B != want.B {
+		t.Fatalf("dot drawn as %v, want %s", c, want.Hex())
+	}
+
+	got := decodeRendered(t, cfg, []byte("custom"), func(img *image.RGBA) image.Image { return img })
+	if !bytes.HasPrefix(got, []byte("custom")) {
+		t.Fatalf("got %q, want prefix %q", got, "custom")
+	}
+}
+
+func TestPaletteInvalidConfig(t *testing.T) {
+	short := DefaultConfig()
+	short.Palette = Palette4
+	wide := DefaultConfig()
+	wide.BitsPerDot = 5
+	shades := DefaultConfig()
+	shades.BitsPerDot = 2
+	shades.Palette = Palette{ // bright and dark red share a hue
+		{R: 0xFF, G: 0x00, B: 0x00},
+		{R: 0x80, G: 0x00, B: 0x00},
+		{R: 0x00, G: 0xFF, B: 0x00},
+		{R: 0x00, G: 0x00, B: 0xFF},
+	}
+
+	for _, c := range []Config{short, wide, shades} {
+		if _, err := NewEncoder(c).Encode([]byte("x")); err != ErrInvalidConfig {
+			t.Errorf("Encode with %d colors at %d bits/dot: err = %v, want ErrInvalidConfig",
+				len(c.Colors()), c.BitsPerDot, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package dotbeam
+
+import "math"
+
+// Pilot dots.
+//
+// With Config.Pilots set, the first dot positions (ring 1 outward) show
+// each palette color once, in palette order, instead of data. DecodeImage
+// samples them alongside the anchors' white and fits an affine color
+// correction (a 3×3 matrix plus offset) by least squares, mapping what the
+// camera saw back onto the palette. Every data dot is corrected before hue
+// matching, so a tint that shifts all hues the same way cancels out.
+
+// minPilotSamples is the fewest reference colors an affine fit needs.
+const minPilotSamples = 4
+
+// pilotCount returns the number of pilot dots a frame carries.
+func (c Config) pilotCount() int {
+	if !c.Pilots {
+		return 0
+	}
+	return len(c.Colors())
+}
+
+// dataDots returns dots without its pilot dots.
+func dataDots(dots []Dot) []Dot {
+	out := make([]Dot, 0, len(dots))
+	for _, dot := range dots {
+		if !dot.Pilot {
+			out = append(out, dot)
+		}
+	}
+	return out
+}
+
+// colorMatrix is an affine color correction: each output channel is a
+// weighted sum of the input R, G, B plus an offset.
+type colorMatrix [3][4]float64
+
+// apply corrects c, clamping the result to 0-255.
+func (m colorMatrix) apply(c rgb) rgb {
+	ch := func(row [4]float64) float64 {
+		return math.Round(min(255, max(0, row[0]*c.R+row[1]*c.G+row[2]*c.B+row[3])))
+	}
+	return rgb{R: ch(m[0]), G: ch(m[1]), B: ch(m[2])}
+}
+
+// fitColorMatrix finds the affine correction that best maps the observed
+// colors onto the expected ones in the least-squares sense. ok is false if
+// there are too few samples or they do not span the color space.
+func fitColorMatrix(observed, expected []rgb) (m colorMatrix, ok bool) {
+	if len(observed) < minPilotSamples || len(observed) != len(expected) {
+		return m, false
+	}
+
+	// Normal equations: (AᵀA) x = Aᵀy with rows A = [r g b 1].
+	ata := make([][]float64, 4)
+	for j := range ata {
+		ata[j] = make([]float64, 4)
+	}
+	aty := make([][]float64, 3)
+	for ch := range aty {
+		aty[ch] = make([]float64, 4)
+	}
+	for i, o := range observed {
+		row := [4]float64{o.R, o.G, o.B, 1}
+		want := [3]float64{expected[i].R, expected[i].G, expected[i].B}
+		for j := range row {
+			for k := range row {
+				ata[j][k] += row[j] * row[k]
+			}
+			for ch := range want {
+				aty[ch][j] += row[j] * want[ch]
+			}
+		}
+	}
+	for ch := range aty {
+		x, ok := solveLinear(ata, aty[ch])
+		if !ok {
+			return colorMatrix{}, false
+		}
+		m[ch] = [4]float64(x)
+	}
+	return m, true
+}
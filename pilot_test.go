@@ -0,0 +1,153 @@
+package dotbeam
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/satindergrewal/dotbeam/sim"
+)
+
+// tint simulates strong channel cross-talk under colored lighting: every
+// pixel goes through the same mix of channels. White stays white.
+func tint(img *image.RGBA) image.Image {
+	out := image.NewRGBA(img.Bounds())
+	mix := func(v float64) uint8 { return uint8(math.Round(min(255, max(0, v)))) }
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			r, g, b := float64(c.R), float64(c.G), float64(c.B)
+			out.SetRGBA(x, y, color.RGBA{
+				R: mix(0.5*r + 0.5*g),
+				G: mix(0.2*r + 0.8*g),
+				B: mix(0.4*g + 0.6*b),
+				A: 0xff,
+			})
+		}
+	}
+	return out
+}
+
+func TestPilotLayout(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Pilots = true
+	cfg.Checksum = true
+	if got := cfg.TotalDots(); got != 52 {
+		t.Fatalf("TotalDots() = %d, want 52 (60 - 8 pilots)", got)
+	}
+
+	f := mustEncode(t, NewEncoder(cfg), []byte("pilots"))[0]
+	if len(f.Dots) != 60 {
+		t.Fatalf("frame has %d dots, want 60", len(f.Dots))
+	}
+	for i, dot := range f.Dots {
+		if want := i < 8; dot.Pilot != want {
+			t.Fatalf("dot %d: Pilot = %v, want %v", i, dot.Pilot, want)
+		}
+		if dot.Pilot && dot.Value != uint8(i) {
+			t.Errorf("pilot %d has value %d, want %d", i, dot.Value, i)
+		}
+	}
+
+	layout := NewLayout(cfg, 1, 1)
+	if !layout.Rings[1].Positions[1].Pilot || layout.Rings[1].Positions[2].Pilot {
+		t.Error("pilots should fill ring 1 and the first 2 positions of ring 2")
+	}
+}
+
+func TestPilotRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Pilots = true
+	cfg.Checksum = true
+	data := bytes.Repeat([]byte("pilot "), 6)
+	if got := roundTrip(t, cfg, data); !bytes.HasPrefix(got, data) {
+		t.Fatalf("round-trip failed: got %q, want prefix %q", got, data)
+	}
+}
+
+func TestPilotsNeedEnoughColors(t *testing.T) {
+	// Two pilots plus white cannot fit a color matrix.
+	cfg := DefaultConfig()
+	cfg.Pilots = true
+	cfg.BitsPerDot = 1
+	if _, err := NewEncoder(cfg).Encode([]byte("x")); err != ErrInvalidConfig {
+		t.Fatalf("Encode with pilots at 1 bit/dot: err = %v, want ErrInvalidConfig", err)
+	}
+	cfg.BitsPerDot = 2
+	if _, err := NewEncoder(cfg).Encode([]byte("x")); err != nil {
+		t.Fatalf("Encode with pilots at 2 bits/dot: err = %v", err)
+	}
+}
+
+func TestPilotsCorrectTint(t *testing.T) {
+	msg := []byte("under a green LED")
+
+	// Without pilots, the tint pushes hues into their neighbours.
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	frame := mustEncode(t, NewEncoder(cfg), msg)[0]
+	dots, _, err := DecodeImage(tint(RenderFrame(frame, NewLayout(cfg, 1, 1), 600, 600)), cfg)
+	if err != nil {
+		t.Fatalf("DecodeImage error: %v", err)
+	}
+	if _, err := NewDecoder(cfg).AddFrame(dots); err == nil {
+		t.Fatal("expected the tint to break decoding without pilots")
+	}
+
+	cfg.Pilots = true
+	got := decodeRendered(t, cfg, msg, tint)
+	if !bytes.HasPrefix(got, msg) {
+		t.Fatalf("round-trip mismatch:\n got: %q\nwant prefix: %q", got, msg)
+	}
+}
+
+func TestPilotsWhiteBalanceError(t *testing.T) {
+	// A warm white balance leaves the anchors at (255, 229, 178): off-white,
+	// and with blue well below the fixed white threshold.
+	warm := func(img *image.RGBA) image.Image {
+		return sim.Channel{WhiteBalance: [3]float64{1.2, 0.9, 0.7}}.Apply(tint(img))
+	}
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	cfg.Pilots = true
+	msg := []byte("warm light, green LED")
+	if got := decodeRendered(t, cfg, msg, warm); !bytes.HasPrefix(got, msg) {
+		t.Fatalf("round-trip mismatch:\n got: %q\nwant prefix: %q", got, msg)
+	}
+}
+
+func TestFitColorMatrix(t *testing.T) {
+	// A known affine map is recovered exactly from the palette.
+	want := colorMatrix{
+		{0.9, 0.1, 0, 5},
+		{0, 0.8, 0.2, -3},
+		{0.1, 0, 0.7, 12},
+	}
+	var observed, expected []rgb
+	for _, c := range Palette8 {
+		e := colorRGB(c)
+		observed = append(observed, e)
+		expected = append(expected, rgb{
+			R: want[0][0]*e.R + want[0][1]*e.G + want[0][2]*e.B + want[0][3],
+			G: want[1][0]*e.R + want[1][1]*e.G + want[1][2]*e.B + want[1][3],
+			B: want[2][0]*e.R + want[2][1]*e.G + want[2][2]*e.B + want[2][3],
+		})
+	}
+	m, ok := fitColorMatrix(observed, expected)
+	if !ok {
+		t.Fatal("fitColorMatrix failed")
+	}
+	for i := range m {
+		for j := range m[i] {
+			if math.Abs(m[i][j]-want[i][j]) > 1e-6 {
+				t.Fatalf("m = %v, want %v", m, want)
+			}
+		}
+	}
+
+	if _, ok := fitColorMatrix(observed[:3], expected[:3]); ok {
+		t.Error("fit with 3 samples should fail")
+	}
+}
@@ -0,0 +1,400 @@
+package dotbeam
+
+// Reed-Solomon forward error correction over GF(256).
+//
+// Each frame's bytes (header, payload and checksum) form the message part
+// of one or more RS codewords; Config.ParityBytes parity bytes per codeword
+// are appended. A codeword with p parity bytes corrects up to p/2 byte
+// errors anywhere in the frame. Frames longer than 255 bytes are split
+// into interleaved codewords so each stays within the GF(256) limit.
+//
+// Polynomials are []byte with the highest-degree coefficient first.
+
+// gfPrim is the primitive polynomial x^8 + x^4 + x^3 + x^2 + 1.
+const gfPrim = 0x11d
+
+var (
+	gfExp [512]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= gfPrim
+		}
+	}
+	// Duplicate the table so gfMul can skip the modulo.
+	for i := 255; i < 512; i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if b == 0 {
+		panic("dotbeam: GF(256) division by zero")
+	}
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])+255-int(gfLog[b]))%255]
+}
+
+// gfPow returns x^power, accepting negative powers.
+func gfPow(x byte, power int) byte {
+	e := (int(gfLog[x]) * power) % 255
+	if e < 0 {
+		e += 255
+	}
+	return gfExp[e]
+}
+
+func gfInverse(x byte) byte {
+	return gfExp[255-int(gfLog[x])]
+}
+
+func polyScale(p []byte, x byte) []byte {
+	out := make([]byte, len(p))
+	for i, c := range p {
+		out[i] = gfMul(c, x)
+	}
+	return out
+}
+
+func polyAdd(p, q []byte) []byte {
+	n := max(len(p), len(q))
+	out := make([]byte, n)
+	for i, c := range p {
+		out[i+n-len(p)] = c
+	}
+	for i, c := range q {
+		out[i+n-len(q)] ^= c
+	}
+	return out
+}
+
+func polyMul(p, q []byte) []byte {
+	out := make([]byte, len(p)+len(q)-1)
+	for j, qc := range q {
+		for i, pc := range p {
+			out[i+j] ^= gfMul(pc, qc)
+		}
+	}
+	return out
+}
+
+// polyEval evaluates p at x using Horner's method.
+func polyEval(p []byte, x byte) byte {
+	y := p[0]
+	for _, c := range p[1:] {
+		y = gfMul(y, x) ^ c
+	}
+	return y
+}
+
+// polyMod returns the remainder of dividend / divisor for a monic divisor.
+func polyMod(dividend, divisor []byte) []byte {
+	out := append([]byte(nil), dividend...)
+	for i := 0; i < len(dividend)-(len(divisor)-1); i++ {
+		coef := out[i]
+		if coef == 0 {
+			continue
+		}
+		for j := 1; j < len(divisor); j++ {
+			out[i+j] ^= gfMul(divisor[j], coef)
+		}
+	}
+	return out[len(out)-(len(divisor)-1):]
+}
+
+// rsGenerator returns the generator polynomial for nsym parity symbols.
+func rsGenerator(nsym int) []byte {
+	g := []byte{1}
+	for i := 0; i < nsym; i++ {
+		g = polyMul(g, []byte{1, gfPow(2, i)})
+	}
+	return g
+}
+
+// rsEncode returns msg followed by nsym parity bytes.
+func rsEncode(msg []byte, nsym int) []byte {
+	gen := rsGenerator(nsym)
+	out := make([]byte, len(msg)+nsym)
+	copy(out, msg)
+	for i := range msg {
+		coef := out[i]
+		if coef == 0 {
+			continue
+		}
+		for j := 1; j < len(gen); j++ {
+			out[i+j] ^= gfMul(gen[j], coef)
+		}
+	}
+	copy(out, msg)
+	return out
+}
+
+// rsSyndromes returns the nsym syndromes of a codeword, with a leading
+// zero so that synd[i+1] = codeword(α^i).
+func rsSyndromes(codeword []byte, nsym int) []byte {
+	synd := make([]byte, nsym+1)
+	for i := 0; i < nsym; i++ {
+		synd[i+1] = polyEval(codeword, gfPow(2, i))
+	}
+	return synd
+}
+
+// rsDecode corrects a codeword in place and returns the number of symbols
+// it repaired. erasures lists byte positions already known to be
+// unreliable; each costs one parity symbol instead of two. The codeword is
+// left untouched if it cannot be corrected.
+func rsDecode(codeword []byte, nsym int, erasures []int) (int, error) {
+	if len(erasures) > nsym {
+		return 0, ErrUncorrectable
+	}
+	msg := append([]byte(nil), codeword...)
+	for _, p := range erasures {
+		msg[p] = 0
+	}
+
+	synd := rsSyndromes(msg, nsym)
+	if allZero(synd) {
+		copy(codeword, msg)
+		return 0, nil
+	}
+
+	fsynd := rsForneySyndromes(synd, erasures, len(msg))
+	errLoc, err := rsErrorLocator(fsynd, nsym, len(erasures))
+	if err != nil {
+		return 0, err
+	}
+	errPos, err := rsFindErrors(reverse(errLoc), len(msg))
+	if err != nil {
+		return 0, err
+	}
+
+	positions := append(append([]int(nil), erasures...), errPos...)
+	if err := rsCorrectErrata(msg, synd, positions); err != nil {
+		return 0, err
+	}
+	if !allZero(rsSyndromes(msg, nsym)) {
+		return 0, ErrUncorrectable
+	}
+
+	corrected := 0
+	for i := range msg {
+		if msg[i] != codeword[i] {
+			corrected++
+		}
+	}
+	copy(codeword, msg)
+	return corrected, nil
+}
+
+// rsForneySyndromes removes the contribution of known erasures so the
+// Berlekamp-Massey step only has to locate the remaining errors.
+func rsForneySyndromes(synd []byte, erasures []int, n int) []byte {
+	fsynd := append([]byte(nil), synd[1:]...)
+	for _, p := range erasures {
+		x := gfPow(2, n-1-p)
+		for j := 0; j < len(fsynd)-1; j++ {
+			fsynd[j] = gfMul(fsynd[j], x) ^ fsynd[j+1]
+		}
+	}
+	return fsynd
+}
+
+// rsErrorLocator runs Berlekamp-Massey over the Forney syndromes to find
+// the locator polynomial of the errors that are not already erasures.
+func rsErrorLocator(synd []byte, nsym, eraseCount int) ([]byte, error) {
+	errLoc := []byte{1}
+	oldLoc := []byte{1}
+	for k := 0; k < nsym-eraseCount; k++ {
+		delta := synd[k]
+		for j := 1; j < len(errLoc); j++ {
+			delta ^= gfMul(errLoc[len(errLoc)-1-j], synd[k-j])
+		}
+		oldLoc = append(oldLoc, 0)
+		if delta != 0 {
+			if len(oldLoc) > len(errLoc) {
+				newLoc := polyScale(oldLoc, delta)
+				oldLoc = polyScale(errLoc, gfInverse(delta))
+				errLoc = newLoc
+			}
+			errLoc = polyAdd(errLoc, polyScale(oldLoc, delta))
+		}
+	}
+
+	for len(errLoc) > 0 && errLoc[0] == 0 {
+		errLoc = errLoc[1:]
+	}
+	errs := len(errLoc) - 1
+	if errs*2+eraseCount > nsym {
+		return nil, ErrUncorrectable
+	}
+	return errLoc, nil
+}
+
+// rsFindErrors locates the roots of the error locator (Chien search) and
+// returns them as byte positions in a codeword of length n.
+func rsFindErrors(errLoc []byte, n int) ([]int, error) {
+	errs := len(errLoc) - 1
+	var pos []int
+	for i := 0; i < n; i++ {
+		if polyEval(errLoc, gfPow(2, i)) == 0 {
+			pos = append(pos, n-1-i)
+		}
+	}
+	if len(pos) != errs {
+		return nil, ErrUncorrectable
+	}
+	return pos, nil
+}
+
+// rsCorrectErrata computes error magnitudes with the Forney algorithm and
+// applies them to msg in place.
+func rsCorrectErrata(msg, synd []byte, positions []int) error {
+	coefPos := make([]int, len(positions))
+	for i, p := range positions {
+		coefPos[i] = len(msg) - 1 - p
+	}
+
+	// Errata locator: product of (1 - x·α^i) over the errata positions.
+	errLoc := []byte{1}
+	for _, i := range coefPos {
+		errLoc = polyMul(errLoc, polyAdd([]byte{1}, []byte{gfPow(2, i), 0}))
+	}
+
+	// Error evaluator: Ω(x) = S(x)·Λ(x) mod x^(nsym+1).
+	nsym := len(errLoc) - 1
+	divisor := make([]byte, nsym+2)
+	divisor[0] = 1
+	errEval := polyMod(polyMul(reverse(synd), errLoc), divisor)
+
+	x := make([]byte, len(coefPos))
+	for i, c := range coefPos {
+		x[i] = gfPow(2, -(255 - c))
+	}
+
+	for i, xi := range x {
+		xiInv := gfInverse(xi)
+
+		// Formal derivative of the errata locator evaluated at xi⁻¹.
+		locPrime := byte(1)
+		for j, xj := range x {
+			if j != i {
+				locPrime = gfMul(locPrime, 1^gfMul(xiInv, xj))
+			}
+		}
+		if locPrime == 0 {
+			return ErrUncorrectable
+		}
+
+		y := gfMul(xi, polyEval(errEval, xiInv))
+		msg[positions[i]] ^= gfDiv(y, locPrime)
+	}
+	return nil
+}
+
+// rsCodewords returns how many interleaved codewords an n-byte frame needs
+// to keep each codeword within 255 bytes.
+func rsCodewords(n int) int {
+	return (n + 254) / 255
+}
+
+// rsEncodeFrame zero-pads msg to the message capacity of an n-byte frame
+// and appends nsym parity bytes per interleaved codeword.
+func rsEncodeFrame(msg []byte, n, nsym int) []byte {
+	c := rsCodewords(n)
+	m := n - c*nsym
+	frame := make([]byte, m, n)
+	copy(frame, msg)
+
+	for j := 0; j < c; j++ {
+		var part []byte
+		for i := j; i < m; i += c {
+			part = append(part, frame[i])
+		}
+		frame = append(frame, rsEncode(part, nsym)[len(part):]...)
+	}
+	return frame
+}
+
+// rsDecodeFrame corrects an n-byte frame produced by rsEncodeFrame and
+// returns its message part and the number of bytes repaired. erasures
+// lists frame byte positions known to be unreliable.
+func rsDecodeFrame(frame []byte, n, nsym int, erasures []int) ([]byte, int, error) {
+	if len(frame) < n {
+		return nil, 0, ErrInvalidFrame
+	}
+	c := rsCodewords(n)
+	m := n - c*nsym
+	msg := append([]byte(nil), frame[:m]...)
+
+	erased := make(map[int]bool, len(erasures))
+	for _, p := range erasures {
+		erased[p] = true
+	}
+
+	total := 0
+	for j := 0; j < c; j++ {
+		// Gather codeword j: interleaved message bytes, then its parity.
+		var idx []int
+		for i := j; i < m; i += c {
+			idx = append(idx, i)
+		}
+		parityStart := m + j*nsym
+		for i := parityStart; i < parityStart+nsym; i++ {
+			idx = append(idx, i)
+		}
+
+		codeword := make([]byte, len(idx))
+		var cwErasures []int
+		for k, i := range idx {
+			codeword[k] = frame[i]
+			if erased[i] {
+				cwErasures = append(cwErasures, k)
+			}
+		}
+
+		corrected, err := rsDecode(codeword, nsym, cwErasures)
+		if err != nil {
+			return nil, 0, err
+		}
+		total += corrected
+		for k, i := range idx {
+			if i < m {
+				msg[i] = codeword[k]
+			}
+		}
+	}
+	return msg, total, nil
+}
+
+func allZero(p []byte) bool {
+	for _, c := range p {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func reverse(p []byte) []byte {
+	out := make([]byte, len(p))
+	for i, c := range p {
+		out[len(p)-1-i] = c
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+package dotbeam
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestGFTables(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if got := gfMul(byte(a), gfInverse(byte(a))); got != 1 {
+			t.Fatalf("%d * inverse(%d) = %d, want 1", a, a, got)
+		}
+		if got := gfDiv(gfMul(byte(a), 7), 7); got != byte(a) {
+			t.Fatalf("(%d*7)/7 = %d", a, got)
+		}
+	}
+}
+
+func TestRSEncodeSyndromesZero(t *testing.T) {
+	codeword := rsEncode([]byte("hello dotbeam"), 6)
+	if !allZero(rsSyndromes(codeword, 6)) {
+		t.Fatal("fresh codeword has non-zero syndromes")
+	}
+}
+
+func TestRSDecodeErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, nsym := range []int{2, 4, 8, 16} {
+		for trial := 0; trial < 50; trial++ {
+			msg := make([]byte, 5+rng.Intn(40))
+			rng.Read(msg)
+			codeword := rsEncode(msg, nsym)
+			received := append([]byte(nil), codeword...)
+
+			nerr := rng.Intn(nsym/2 + 1)
+			for _, p := range rng.Perm(len(received))[:nerr] {
+				received[p] ^= byte(1 + rng.Intn(255))
+			}
+
+			corrected, err := rsDecode(received, nsym, nil)
+			if err != nil {
+				t.Fatalf("nsym=%d errors=%d: %v", nsym, nerr, err)
+			}
+			if corrected != nerr {
+				t.Errorf("nsym=%d: corrected %d, want %d", nsym, corrected, nerr)
+			}
+			if !bytes.Equal(received, codeword) {
+				t.Fatalf("nsym=%d errors=%d: codeword not restored", nsym, nerr)
+			}
+		}
+	}
+}
+
+func TestRSDecodeErasures(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	const nsym = 8
+	msg := []byte("erasures cost half as much parity")
+	codeword := rsEncode(msg, nsym)
+
+	// 4 erasures + 2 errors = 4 + 2*2 = 8 parity symbols.
+	received := append([]byte(nil), codeword...)
+	perm := rng.Perm(len(received))
+	erasures := perm[:4]
+	for _, p := range perm[:6] {
+		received[p] ^= 0x5a
+	}
+
+	if _, err := rsDecode(received, nsym, erasures); err != nil {
+		t.Fatalf("rsDecode error: %v", err)
+	}
+	if !bytes.Equal(received, codeword) {
+		t.Fatal("codeword not restored")
+	}
+}
+
+func TestRSDecodeTooManyErasures(t *testing.T) {
+	codeword := rsEncode([]byte("abc"), 2)
+	if _, err := rsDecode(codeword, 2, []int{0, 1, 2}); err != ErrUncorrectable {
+		t.Fatalf("err = %v, want ErrUncorrectable", err)
+	}
+}
+
+func TestRSFrameInterleaving(t *testing.T) {
+	// 600-byte frames need three codewords.
+	const n, nsym = 600, 10
+	msg := bytes.Repeat([]byte("interleaved "), 40)
+	frame := rsEncodeFrame(msg, n, nsym)
+	if len(frame) != n {
+		t.Fatalf("frame length = %d, want %d", len(frame), n)
+	}
+
+	// A burst of 12 consecutive bad bytes spreads across codewords.
+	for i := 100; i < 112; i++ {
+		frame[i] ^= 0xff
+	}
+	got, corrected, err := rsDecodeFrame(frame, n, nsym, nil)
+	if err != nil {
+		t.Fatalf("rsDecodeFrame error: %v", err)
+	}
+	if corrected != 12 {
+		t.Errorf("corrected = %d, want 12", corrected)
+	}
+	if !bytes.HasPrefix(got, msg) {
+		t.Fatal("message not restored")
+	}
+}
+
+func TestParityBytesPerFrame(t *testing.T) {
+	c := DefaultConfig()
+	c.ParityBytes = 4
+	// 22 bytes - 4 parity - 2 header = 16
+	if got := c.BytesPerFrame(); got != 16 {
+		t.Fatalf("expected 16 bytes per frame, got %d", got)
+	}
+}
+
+func TestDecoderRejectsOversizedParity(t *testing.T) {
+	frames := mustEncode(t, NewEncoder(DefaultConfig()), []byte("parity"))
+
+	// 100 is more parity than the frame holds; a negative count would
+	// grow the payload instead.
+	for _, parity := range []int{100, -4} {
+		config := DefaultConfig()
+		config.ParityBytes = parity
+		dec := NewDecoder(config)
+		if _, err := dec.AddFrame(frames[0].Dots); err != ErrInvalidConfig {
+			t.Fatalf("AddFrame with ParityBytes=%d: err = %v, want ErrInvalidConfig", parity, err)
+		}
+	}
+}
+
+func TestDecoderCorrectsMisreadDots(t *testing.T) {
+	config := DefaultConfig()
+	config.ParityBytes = 4
+	config.Checksum = true
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("RS"), 20)
+	frames := mustEncode(t, enc, data)
+	for _, f := range frames {
+		dots := append([]Dot(nil), f.Dots...)
+		// Two misread dots in different bytes of the frame.
+		dots[3].Value ^= 0x01
+		dots[40].Value ^= 0x04
+
+		if _, err := dec.AddFrame(dots); err != nil {
+			t.Fatalf("AddFrame(%d) error: %v", f.Index, err)
+		}
+		if dec.Corrected() != 2 {
+			t.Errorf("frame %d: Corrected() = %d, want 2", f.Index, dec.Corrected())
+		}
+	}
+
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatalf("round-trip failed:\n got: %q\nwant prefix: %q", got, data)
+	}
+}
+
+// softFrame returns a copy of dots read with full confidence except for
+// the listed dots, which are misread with low confidence.
+func softFrame(dots []Dot, weak ...int) []Dot {
+	out := append([]Dot(nil), dots...)
+	for i := range out {
+		out[i].Confidence = 1
+	}
+	for _, i := range weak {
+		out[i].Value ^= 0x02
+		out[i].Confidence = 0.55
+	}
+	return out
+}
+
+func TestDecoderErasesLowConfidenceDots(t *testing.T) {
+	config := DefaultConfig()
+	config.ParityBytes = 4
+	config.Checksum = true
+	enc := NewEncoder(config)
+
+	data := bytes.Repeat([]byte("soft"), 8)
+	frames := mustEncode(t, enc, data)
+
+	// Four misread dots in four different bytes: twice what 4 parity
+	// bytes can correct as errors, exactly what they can fill as erasures.
+	weak := []int{3, 8, 11, 40}
+	hard := NewDecoder(config)
+	if _, err := hard.AddFrame(softFrame(frames[0].Dots, weak...)); err == nil {
+		t.Fatal("expected hard-decision decoding to fail")
+	}
+
+	config.ErasureThreshold = 0.7
+	dec := NewDecoder(config)
+	for _, f := range frames {
+		if _, err := dec.AddFrame(softFrame(f.Dots, weak...)); err != nil {
+			t.Fatalf("AddFrame(%d) error: %v", f.Index, err)
+		}
+		if dec.Corrected() != 4 {
+			t.Errorf("frame %d: Corrected() = %d, want 4", f.Index, dec.Corrected())
+		}
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatalf("round-trip failed:\n got: %q\nwant prefix: %q", got, data)
+	}
+}
+
+func TestDecoderErasureFallback(t *testing.T) {
+	config := DefaultConfig()
+	config.ParityBytes = 4
+	config.Checksum = true
+	config.ErasureThreshold = 0.7
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	f := mustEncode(t, enc, []byte("fallback"))[0]
+	// Four dots are doubtful but right; one confident dot is wrong. The
+	// erasures leave no parity for the error, so RS miscorrects, the
+	// checksum catches it and the decoder retries without erasures.
+	dots := softFrame(f.Dots)
+	for _, i := range []int{3, 8, 11, 40} {
+		dots[i].Confidence = 0.55
+	}
+	dots[20].Value ^= 0x04
+
+	if _, err := dec.AddFrame(dots); err != nil {
+		t.Fatalf("AddFrame error: %v", err)
+	}
+	if dec.Corrected() != 1 {
+		t.Errorf("Corrected() = %d, want 1", dec.Corrected())
+	}
+}
@@ -3,6 +3,8 @@ package dotbeam
 import (
 	"image"
 	"image/color"
+	"image/draw"
+	"image/gif"
 	"math"
 )
 
@@ -13,6 +15,7 @@ var bgColor = color.RGBA{R: 0x0a, G: 0x0a, B: 0x1a, A: 0xff}
 const (
 	dataDotRadiusFactor   = 0.06  // matches renderer.js
 	anchorDotRadiusFactor = 0.065 // slightly larger than data dots
+	cueDotRadiusFactor    = 0.04  // small enough not to pass for an anchor
 )
 
 // RenderFrame draws a single dotbeam frame as an RGBA image.
@@ -35,22 +38,66 @@ func RenderFrame(frame Frame, layout Layout, width, height int) *image.RGBA {
 	anchorDotR := anchorDotRadiusFactor * scale
 
 	// Draw data dots
+	palette := layout.Config.Colors()
 	for _, dot := range frame.Dots {
 		px, py := ScaleToCanvas(dot.X, dot.Y, w, h)
-		c := DefaultColors[dot.Value&0x07]
+		c := palette.color(dot.Value)
 		fillCircle(img, px, py, dataDotR, color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff})
 	}
 
-	// Draw anchor dots (white, on top)
+	// Draw anchor dots and the orientation cue (white, on top)
 	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
 	for _, anchor := range layout.Anchors {
 		px, py := ScaleToCanvas(anchor.X, anchor.Y, w, h)
 		fillCircle(img, px, py, anchorDotR, white)
 	}
+	px, py := ScaleToCanvas(layout.Cue.X, layout.Cue.Y, w, h)
+	fillCircle(img, px, py, cueDotRadiusFactor*scale, white)
 
 	return img
 }
 
+// gifPalette is the fixed GIF palette: background, the data colors and the
+// anchor white. Rendered frames use no other colors, so mapping onto it is
+// exact.
+func gifPalette(colors Palette) color.Palette {
+	p := color.Palette{bgColor}
+	for _, c := range colors {
+		p = append(p, color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff})
+	}
+	return append(p, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+}
+
+// RenderGIF draws frames as a looping animated GIF. Frame delays follow
+// layout.Config.FPS. The layout should be created with NewLayout(config, 1, 1).
+// A GIF holds at most 256 colors, two of which are the background and the
+// anchor white, so palettes of more than 254 colors (BitsPerDot 8) return
+// ErrGIFPalette.
+func RenderGIF(frames []Frame, layout Layout, width, height int) (*gif.GIF, error) {
+	palette := gifPalette(layout.Config.Colors())
+	if len(palette) > 256 {
+		return nil, ErrGIFPalette
+	}
+
+	fps := layout.Config.FPS
+	if fps <= 0 {
+		fps = DefaultConfig().FPS
+	}
+	delay := max(1, 100/fps) // GIF delays are in 1/100 s
+
+	bounds := image.Rect(0, 0, width, height)
+	g := &gif.GIF{
+		Config: image.Config{ColorModel: palette, Width: width, Height: height},
+	}
+	for _, frame := range frames {
+		img := image.NewPaletted(bounds, palette)
+		draw.Draw(img, bounds, RenderFrame(frame, layout, width, height), image.Point{}, draw.Src)
+		g.Image = append(g.Image, img)
+		g.Delay = append(g.Delay, delay)
+	}
+	return g, nil
+}
+
 // fillCircle draws a filled circle on the image.
 func fillCircle(img *image.RGBA, cx, cy, radius float64, col color.RGBA) {
 	bounds := img.Bounds()
@@ -1,7 +1,10 @@
 package dotbeam
 
 import (
+	"bytes"
 	"image"
+	"image/gif"
+	"math"
 	"strings"
 	"testing"
 )
@@ -58,7 +61,7 @@ func TestRenderRoundTrip(t *testing.T) {
 	msg := "Hello"
 	cfg := DefaultConfig()
 	enc := NewEncoder(cfg)
-	frames := enc.Encode([]byte(msg))
+	frames := mustEncode(t, enc, []byte(msg))
 
 	if len(frames) == 0 {
 		t.Fatal("encoder produced no frames")
@@ -102,7 +105,7 @@ func TestRenderRoundTripLonger(t *testing.T) {
 	msg := "The quick brown fox jumps over the lazy dog"
 	cfg := DefaultConfig()
 	enc := NewEncoder(cfg)
-	frames := enc.Encode([]byte(msg))
+	frames := mustEncode(t, enc, []byte(msg))
 
 	if len(frames) < 2 {
 		t.Fatalf("expected multiple frames for long message, got %d", len(frames))
@@ -143,7 +146,7 @@ func TestRenderRoundTripLonger(t *testing.T) {
 func TestRenderFrameSize(t *testing.T) {
 	cfg := DefaultConfig()
 	enc := NewEncoder(cfg)
-	frames := enc.Encode([]byte("test"))
+	frames := mustEncode(t, enc, []byte("test"))
 	layout := NewLayout(cfg, 1, 1)
 
 	img := RenderFrame(frames[0], layout, 400, 400)
@@ -159,3 +162,79 @@ func TestRenderFrameSize(t *testing.T) {
 		t.Errorf("center pixel = (%d,%d,%d), expected dark background", c.R, c.G, c.B)
 	}
 }
+
+func TestRenderGIF(t *testing.T) {
+	cfg := DefaultConfig()
+	msg := []byte("animated without ffmpeg, straight from image/gif")
+	frames := mustEncode(t, NewEncoder(cfg), msg)
+	layout := NewLayout(cfg, 1, 1)
+
+	anim, err := RenderGIF(frames, layout, 500, 500)
+	if err != nil {
+		t.Fatalf("RenderGIF error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("EncodeAll error: %v", err)
+	}
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("DecodeAll error: %v", err)
+	}
+	if len(g.Image) != len(frames) {
+		t.Fatalf("GIF has %d frames, want %d", len(g.Image), len(frames))
+	}
+	if g.Delay[0] != 100/cfg.FPS {
+		t.Errorf("delay = %d, want %d", g.Delay[0], 100/cfg.FPS)
+	}
+	if g.LoopCount != 0 {
+		t.Errorf("LoopCount = %d, want 0 (forever)", g.LoopCount)
+	}
+
+	// The encoder pads the color table to a power of two; the fixed
+	// palette must come first.
+	want := gifPalette(cfg.Colors())
+	for i, c := range want {
+		r, gr, b, _ := g.Image[0].Palette[i].RGBA()
+		wr, wg, wb, _ := c.RGBA()
+		if r != wr || gr != wg || b != wb {
+			t.Errorf("palette[%d] = %v, want %v", i, g.Image[0].Palette[i], c)
+		}
+	}
+
+	dec := NewDecoder(cfg)
+	for i, img := range g.Image {
+		dots, _, err := DecodeImage(img, cfg)
+		if err != nil {
+			t.Fatalf("frame %d: DecodeImage error: %v", i, err)
+		}
+		if _, err := dec.AddFrame(dots); err != nil {
+			t.Fatalf("frame %d: AddFrame error: %v", i, err)
+		}
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, msg) {
+		t.Fatalf("round-trip mismatch:\n got: %q\nwant prefix: %q", got, msg)
+	}
+}
+
+func TestRenderGIFPaletteTooLarge(t *testing.T) {
+	// 256 fully saturated hues, 1.4° apart: valid for encoding, but with
+	// the background and anchor white they overflow a GIF color table.
+	cfg := DefaultConfig()
+	cfg.BitsPerDot = 8
+	for i := range 256 {
+		h := float64(i) * 6 / 256
+		x := uint8(math.Round(255 * (1 - math.Abs(math.Mod(h, 2)-1))))
+		c := [6]Color{{255, x, 0}, {x, 255, 0}, {0, 255, x}, {0, x, 255}, {x, 0, 255}, {255, 0, x}}[int(h)]
+		cfg.Palette = append(cfg.Palette, c)
+	}
+	frames := mustEncode(t, NewEncoder(cfg), []byte("256 colors"))
+
+	if _, err := RenderGIF(frames, NewLayout(cfg, 1, 1), 200, 200); err != ErrGIFPalette {
+		t.Fatalf("RenderGIF with 256 colors: err = %v, want ErrGIFPalette", err)
+	}
+}
@@ -0,0 +1,632 @@
+package dotbeam
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// Scanner tuning. These mirror the constants in web/static/scanner.js so
+// the Go and browser pipelines behave the same on the same capture.
+const (
+	blobThreshold       = 200   // all channels above this count as white
+	whiteShare          = 0.002 // brightest share of pixels that sets the white level
+	minWhiteLevel       = 64    // darker white levels are not trusted
+	blobCellsAcross     = 90    // grid resolution (8px cells at 720p)
+	minBlobCells        = 4     // smaller blobs are noise or text
+	maxBlobCells        = 50    // larger blobs are screen glare
+	maxAnchorSaturation = 0.25  // anchors are white, data dots are not
+	maxTriangleSkew     = 0.3   // side lengths within 30% of the mean
+	minTriangleSide     = 20    // pixels
+	maxBlobSizeRatio    = 3     // anchors are all the same kind of dot
+	maxCenterBrightness = 80    // pattern center is the dark background
+	minAnchorBrightness = 100   // below this, skip white balance
+	maxWhiteBalanceGain = 2.0
+	maxCueOffset        = 0.2 // normalized distance from the predicted cue
+)
+
+// Transform maps normalized layout coordinates onto image pixels.
+type Transform struct {
+	CenterX, CenterY float64 // pattern center in pixels
+	Scale            float64 // pixels per normalized unit
+	Rotation         float64 // radians, positive is clockwise on screen
+	Mirrored         bool    // captured mirror image (front camera, reflection)
+
+	// Homography, when set, maps layout points to pixels including
+	// perspective; the fields above then describe it only approximately.
+	Homography Homography
+}
+
+// Apply maps a normalized layout point to image pixel coordinates.
+// A mirrored transform flips x before rotating.
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	if t.Homography != (Homography{}) {
+		return t.Homography.Apply(x, y)
+	}
+	if t.Mirrored {
+		x = -x
+	}
+	cosR := math.Cos(t.Rotation)
+	sinR := math.Sin(t.Rotation)
+	rx := x*cosR - y*sinR
+	ry := x*sinR + y*cosR
+	return t.CenterX + rx*t.Scale, t.CenterY + ry*t.Scale
+}
+
+// blob is a bright connected region found by findWhiteBlobs.
+type blob struct {
+	X, Y float64
+	Size int // grid cells
+}
+
+// rgb is an 8-bit color sample.
+type rgb struct {
+	R, G, B float64
+}
+
+// DecodeImage locates a dotbeam constellation in a photo or screenshot and
+// reads its data dots. It mirrors the browser scanner: white-blob anchor
+// detection, equilateral-triangle validation, transform derivation, white
+// balance from the anchors, peak-seeking sampling and hue matching. When
+// the orientation cue is visible it also corrects mirroring and perspective.
+//
+// The returned dots can be passed straight to Decoder.AddFrame. Pixel
+// coordinates in the Transform are relative to img.Bounds().Min.
+func DecodeImage(img image.Image, cfg Config) ([]Dot, Transform, error) {
+	rgba := toRGBA(img)
+
+	blobs := findWhiteBlobs(rgba)
+	t, anchors, ok := deriveTransform(blobs, rgba)
+	if !ok {
+		return nil, Transform{}, ErrNoAnchors
+	}
+
+	sampleRadius := max(2, int(t.Scale*0.03))
+	gain, white := calibrateWhiteBalance(rgba, anchors, sampleRadius)
+	balance := func(c rgb) rgb {
+		return rgb{
+			R: math.Min(255, math.Round(c.R*gain.R)),
+			G: math.Min(255, math.Round(c.G*gain.G)),
+			B: math.Min(255, math.Round(c.B*gain.B)),
+		}
+	}
+
+	layout := NewLayout(cfg, 1, 1)
+	palette := cfg.Colors()
+	var dots []Dot
+	var samples []rgb
+	for _, ring := range layout.Rings {
+		for j, pos := range ring.Positions {
+			samples = append(samples, balance(sampleDot(rgba, t, pos.X, pos.Y)))
+			dots = append(dots, Dot{
+				Ring:  ring.DotCount / 6,
+				Index: j,
+				X:     pos.X,
+				Y:     pos.Y,
+				Pilot: pos.Pilot,
+			})
+		}
+	}
+
+	// Pilot dots (and the white anchors) are known colors; correct every
+	// sample with the affine map that best restores them.
+	if cfg.Pilots {
+		observed := []rgb{balance(white)}
+		expected := []rgb{{R: 255, G: 255, B: 255}}
+		pilot := uint8(0)
+		for i, dot := range dots {
+			if dot.Pilot {
+				observed = append(observed, samples[i])
+				expected = append(expected, colorRGB(palette.color(pilot)))
+				pilot++
+			}
+		}
+		if m, ok := fitColorMatrix(observed, expected); ok {
+			for i := range samples {
+				samples[i] = m.apply(samples[i])
+			}
+		}
+	}
+
+	for i := range dots {
+		dots[i].Value, dots[i].Confidence = matchColor(samples[i], palette)
+	}
+	return dots, t, nil
+}
+
+// toRGBA returns img as an *image.RGBA with its origin at (0, 0).
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok && b.Min == (image.Point{}) {
+		return rgba
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// findWhiteBlobs finds bright, unsaturated blobs on a coarse grid and
+// returns them sorted by size, largest first.
+//
+// Pass 1 marks grid cells containing pixels with every channel above
+// blobThreshold and flood-fills them into blobs. Pass 2 rejects blobs that
+// are too small, too large, or whose centre is colourful rather than white.
+// Both passes measure against the white level of the capture rather than
+// pure white, so a white-balance or exposure error that keeps the anchors
+// the brightest dots does not hide them.
+func findWhiteBlobs(img *image.RGBA) []blob {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	cell := max(2, min(width, height)/blobCellsAcross)
+
+	level := whiteLevel(img)
+	var threshold [3]uint8
+	for ch := range threshold {
+		threshold[ch] = uint8(level[ch] * blobThreshold / 255)
+	}
+
+	gridW := (width + cell - 1) / cell
+	gridH := (height + cell - 1) / cell
+	grid := make([]bool, gridW*gridH)
+
+	// Per-cell sums of white pixel positions, for sub-cell centroids.
+	type cellSum struct{ x, y, n float64 }
+	sums := make([]cellSum, len(grid))
+
+	for y := 0; y < height; y += 2 {
+		for x := 0; x < width; x += 2 {
+			c := img.RGBAAt(x, y)
+			if c.R > threshold[0] && c.G > threshold[1] && c.B > threshold[2] {
+				ci := (y/cell)*gridW + x/cell
+				grid[ci] = true
+				sums[ci].x += float64(x)
+				sums[ci].y += float64(y)
+				sums[ci].n++
+			}
+		}
+	}
+
+	visited := make([]bool, len(grid))
+	var blobs []blob
+	for start := range grid {
+		if !grid[start] || visited[start] {
+			continue
+		}
+
+		// Flood-fill connected cells (4-neighbourhood).
+		queue := []int{start}
+		visited[start] = true
+		var cells []int
+		for len(queue) > 0 {
+			ci := queue[0]
+			queue = queue[1:]
+			cells = append(cells, ci)
+
+			cx, cy := ci%gridW, ci/gridW
+			neighbors := [4]int{-1, -1, -1, -1}
+			if cy > 0 {
+				neighbors[0] = ci - gridW
+			}
+			if cy < gridH-1 {
+				neighbors[1] = ci + gridW
+			}
+			if cx > 0 {
+				neighbors[2] = ci - 1
+			}
+			if cx < gridW-1 {
+				neighbors[3] = ci + 1
+			}
+			for _, n := range neighbors {
+				if n >= 0 && grid[n] && !visited[n] {
+					visited[n] = true
+					queue = append(queue, n)
+				}
+			}
+		}
+
+		if len(cells) < minBlobCells || len(cells) > maxBlobCells {
+			continue
+		}
+
+		var total cellSum
+		for _, ci := range cells {
+			total.x += sums[ci].x
+			total.y += sums[ci].y
+			total.n += sums[ci].n
+		}
+		centX := total.x/total.n + 0.5
+		centY := total.y/total.n + 0.5
+
+		// The blob centre must actually be white, not a bright colour.
+		c := samplePatch(img, int(math.Round(centX)), int(math.Round(centY)), 2)
+		c = rgb{R: c.R * 255 / level[0], G: c.G * 255 / level[1], B: c.B * 255 / level[2]}
+		if saturation(c) > maxAnchorSaturation {
+			continue
+		}
+
+		blobs = append(blobs, blob{X: centX, Y: centY, Size: len(cells)})
+	}
+
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return blobs[i].Size > blobs[j].Size
+	})
+	return blobs
+}
+
+// deriveTransform searches the largest blobs for an anchor triple and
+// derives the pattern transform from it.
+func deriveTransform(blobs []blob, img *image.RGBA) (Transform, [3]blob, bool) {
+	limit := min(len(blobs), 10)
+	for i := 0; i < limit-2; i++ {
+		for j := i + 1; j < limit-1; j++ {
+			for k := j + 1; k < limit; k++ {
+				triple := [3]blob{blobs[i], blobs[j], blobs[k]}
+				if !isEquilateral(triple) {
+					continue
+				}
+
+				cx := (triple[0].X + triple[1].X + triple[2].X) / 3
+				cy := (triple[0].Y + triple[1].Y + triple[2].Y) / 3
+
+				// The pattern center is dark background; false triples
+				// (UI text, reflections) have bright centers.
+				if brightness(samplePoint(img, int(math.Round(cx)), int(math.Round(cy)), 3)) > maxCenterBrightness {
+					continue
+				}
+
+				// Mean anchor distance corresponds to the anchor radius.
+				avgDist := 0.0
+				for _, a := range triple {
+					avgDist += math.Hypot(a.X-cx, a.Y-cy)
+				}
+				avgDist /= 3
+
+				// The 270° anchor sits at (0, +0.82). The orientation cue
+				// identifies it and tells a mirror image apart; without a
+				// cue, assume it is the bottommost blob.
+				labelled, cue, mirrored, ok := findCue(blobs, triple, avgDist/anchorRadius)
+				if !ok {
+					labelled = triple
+					for m := 1; m < 3; m++ {
+						if labelled[m].Y > labelled[0].Y {
+							labelled[0], labelled[m] = labelled[m], labelled[0]
+						}
+					}
+				}
+				rotation := math.Atan2(labelled[0].Y-cy, labelled[0].X-cx) - math.Pi/2
+				for rotation > math.Pi {
+					rotation -= 2 * math.Pi
+				}
+				for rotation < -math.Pi {
+					rotation += 2 * math.Pi
+				}
+
+				t := Transform{
+					CenterX:  cx,
+					CenterY:  cy,
+					Scale:    avgDist / anchorRadius,
+					Rotation: rotation,
+					Mirrored: mirrored,
+				}
+				if ok {
+					// Four known points pin down the perspective.
+					if h, ok := anchorHomography(labelled, cue); ok {
+						t.Homography = h
+						t.CenterX, t.CenterY = h.Apply(0, 0)
+					}
+				}
+				return t, triple, true
+			}
+		}
+	}
+	return Transform{}, [3]blob{}, false
+}
+
+// findCue looks for the orientation cue beside an anchor triple. Each way
+// of labelling the triple as A0, A1, A2 fixes an affine map from the
+// layout, and the labelling that finds a blob where it puts the cue wins.
+// It returns the labelled anchors, the cue, and whether the labelling is
+// mirrored.
+func findCue(blobs []blob, triple [3]blob, scale float64) (labelled [3]blob, cue blob, mirrored, ok bool) {
+	layout := NewLayout(Config{}, 1, 1)
+	a := make([][]float64, 3)
+	for i, p := range layout.Anchors {
+		a[i] = []float64{p.X, p.Y, 1}
+	}
+
+	best := maxCueOffset * scale
+	for _, order := range [6][3]int{{0, 1, 2}, {1, 2, 0}, {2, 0, 1}, {0, 2, 1}, {1, 0, 2}, {2, 1, 0}} {
+		xs := make([]float64, 3)
+		ys := make([]float64, 3)
+		for i, k := range order {
+			xs[i], ys[i] = triple[k].X, triple[k].Y
+		}
+		mx, okx := solveLinear(a, xs)
+		my, oky := solveLinear(a, ys)
+		if !okx || !oky {
+			continue
+		}
+		px := mx[0]*layout.Cue.X + mx[1]*layout.Cue.Y + mx[2]
+		py := my[0]*layout.Cue.X + my[1]*layout.Cue.Y + my[2]
+		for _, b := range blobs {
+			if b == triple[0] || b == triple[1] || b == triple[2] {
+				continue
+			}
+			if d := math.Hypot(b.X-px, b.Y-py); d < best {
+				best = d
+				labelled = [3]blob{triple[order[0]], triple[order[1]], triple[order[2]]}
+				cue = b
+				// A mirror image reverses the map's orientation.
+				mirrored = mx[0]*my[1]-mx[1]*my[0] < 0
+				ok = true
+			}
+		}
+	}
+	return labelled, cue, mirrored, ok
+}
+
+// anchorHomography fits the homography through the labelled anchors and
+// the cue.
+func anchorHomography(anchors [3]blob, cue blob) (Homography, bool) {
+	layout := NewLayout(Config{}, 1, 1)
+	src := [4]Anchor{layout.Anchors[0], layout.Anchors[1], layout.Anchors[2], layout.Cue}
+	var dst [4]Anchor
+	for i, b := range append(anchors[:], cue) {
+		dst[i] = Anchor{X: b.X, Y: b.Y}
+	}
+	return fitHomography(src, dst)
+}
+
+// isEquilateral reports whether three blobs form an approximately
+// equilateral triangle and are of similar size.
+func isEquilateral(t [3]blob) bool {
+	d1 := math.Hypot(t[0].X-t[1].X, t[0].Y-t[1].Y)
+	d2 := math.Hypot(t[1].X-t[2].X, t[1].Y-t[2].Y)
+	d3 := math.Hypot(t[0].X-t[2].X, t[0].Y-t[2].Y)
+	avg := (d1 + d2 + d3) / 3
+	if avg < minTriangleSide {
+		return false
+	}
+	for _, d := range []float64{d1, d2, d3} {
+		if math.Abs(d-avg)/avg >= maxTriangleSkew {
+			return false
+		}
+	}
+
+	minSize := min(t[0].Size, t[1].Size, t[2].Size)
+	maxSize := max(t[0].Size, t[1].Size, t[2].Size)
+	return maxSize <= minSize*maxBlobSizeRatio
+}
+
+// whiteLevel estimates the level white reaches in each channel of img: the
+// value the brightest whiteShare of pixels reach, at least minWhiteLevel.
+// The anchors are white and every saturated data dot maxes out some
+// channel, so under a channel gain it follows the anchors.
+func whiteLevel(img *image.RGBA) [3]float64 {
+	b := img.Bounds()
+	var hist [3][256]int
+	n := 0
+	for y := 0; y < b.Dy(); y += 2 {
+		for x := 0; x < b.Dx(); x += 2 {
+			c := img.RGBAAt(x, y)
+			hist[0][c.R]++
+			hist[1][c.G]++
+			hist[2][c.B]++
+			n++
+		}
+	}
+
+	var level [3]float64
+	for ch := range hist {
+		v, count := 255, 0
+		for ; v > minWhiteLevel; v-- {
+			if count += hist[ch][v]; float64(count) >= whiteShare*float64(n) {
+				break
+			}
+		}
+		level[ch] = float64(v)
+	}
+	return level
+}
+
+// calibrateWhiteBalance derives per-channel gains from the anchors, which
+// are known to be pure white. It also returns the anchors' average color.
+func calibrateWhiteBalance(img *image.RGBA, anchors [3]blob, sampleRadius int) (gain, avg rgb) {
+	var sum rgb
+	for _, a := range anchors {
+		c := samplePoint(img, int(math.Round(a.X)), int(math.Round(a.Y)), sampleRadius)
+		sum.R += c.R
+		sum.G += c.G
+		sum.B += c.B
+	}
+	avg = rgb{R: sum.R / 3, G: sum.G / 3, B: sum.B / 3}
+
+	gain = rgb{R: 1, G: 1, B: 1}
+	if brightness(avg) < minAnchorBrightness {
+		// Anchors too dark to trust as a white reference.
+		return gain, avg
+	}
+	channelGain := func(v float64) float64 {
+		if v <= 20 {
+			return 1
+		}
+		return math.Min(maxWhiteBalanceGain, 255/v)
+	}
+	gain.R = channelGain(avg.R)
+	gain.G = channelGain(avg.G)
+	gain.B = channelGain(avg.B)
+	return gain, avg
+}
+
+// sampleDot reads the colour of the data dot at normalized position
+// (x, y). It searches a small grid around the expected centre and keeps
+// the brightest sample, which is the one closest to the actual dot.
+func sampleDot(img *image.RGBA, t Transform, x, y float64) rgb {
+	fx, fy := t.Apply(x, y)
+	px := int(math.Round(fx))
+	py := int(math.Round(fy))
+
+	sampleRadius := max(2, int(t.Scale*0.03))
+	searchRadius := max(3, int(t.Scale*0.03))
+	searchStep := max(2, searchRadius/2)
+
+	var best rgb
+	bestBrightness := -1.0
+	for sy := -searchRadius; sy <= searchRadius; sy += searchStep {
+		for sx := -searchRadius; sx <= searchRadius; sx += searchStep {
+			c := samplePoint(img, px+sx, py+sy, sampleRadius)
+			if b := c.R + c.G + c.B; b > bestBrightness {
+				bestBrightness = b
+				best = c
+			}
+		}
+	}
+	return best
+}
+
+// samplePoint averages a circular patch of pixels centred on (px, py).
+func samplePoint(img *image.RGBA, px, py, radius int) rgb {
+	b := img.Bounds()
+	r2 := radius * radius
+	var sum rgb
+	count := 0
+	for sy := -radius; sy <= radius; sy++ {
+		for sx := -radius; sx <= radius; sx++ {
+			if sx*sx+sy*sy > r2 {
+				continue
+			}
+			x, y := px+sx, py+sy
+			if x < b.Min.X || x >= b.Max.X || y < b.Min.Y || y >= b.Max.Y {
+				continue
+			}
+			c := img.RGBAAt(x, y)
+			sum.R += float64(c.R)
+			sum.G += float64(c.G)
+			sum.B += float64(c.B)
+			count++
+		}
+	}
+	if count == 0 {
+		return rgb{}
+	}
+	n := float64(count)
+	return rgb{R: math.Round(sum.R / n), G: math.Round(sum.G / n), B: math.Round(sum.B / n)}
+}
+
+// samplePatch averages a square patch of pixels centred on (px, py).
+func samplePatch(img *image.RGBA, px, py, radius int) rgb {
+	b := img.Bounds()
+	var sum rgb
+	count := 0
+	for y := py - radius; y <= py+radius; y++ {
+		for x := px - radius; x <= px+radius; x++ {
+			if x < b.Min.X || x >= b.Max.X || y < b.Min.Y || y >= b.Max.Y {
+				continue
+			}
+			c := img.RGBAAt(x, y)
+			sum.R += float64(c.R)
+			sum.G += float64(c.G)
+			sum.B += float64(c.B)
+			count++
+		}
+	}
+	if count == 0 {
+		return rgb{}
+	}
+	n := float64(count)
+	return rgb{R: sum.R / n, G: sum.G / n, B: sum.B / n}
+}
+
+// matchColor returns the index of the palette color nearest to c and how
+// clearly it won: d2 / (d1 + d2) for the distances to the nearest and
+// second-nearest colors, 0.5 for a tie up to 1.0 for an exact match.
+// Saturated samples are matched by hue, which is invariant to camera
+// exposure, so palettes must not repeat a hue (Config.validate rejects
+// them); dark or achromatic samples fall back to RGB distance.
+func matchColor(c rgb, palette Palette) (uint8, float64) {
+	dist := func(p Color) float64 {
+		dr := c.R - float64(p.R)
+		dg := c.G - float64(p.G)
+		db := c.B - float64(p.B)
+		return math.Sqrt(dr*dr + dg*dg + db*db)
+	}
+	if saturation(c) > 0.15 && math.Max(c.R, math.Max(c.G, c.B)) > 30 {
+		if h := hue(c); h >= 0 {
+			dist = func(p Color) float64 {
+				ph := hue(colorRGB(p))
+				if ph < 0 {
+					return 180 // achromatic palette colors never win on hue
+				}
+				return hueDist(h, ph)
+			}
+		}
+	}
+
+	best := uint8(0)
+	bestDist, secondDist := math.Inf(1), math.Inf(1)
+	for i, p := range palette {
+		d := dist(p)
+		if d < bestDist {
+			secondDist = bestDist
+			bestDist = d
+			best = uint8(i)
+		} else if d < secondDist {
+			secondDist = d
+		}
+	}
+	if bestDist+secondDist == 0 {
+		return best, 0.5
+	}
+	return best, secondDist / (bestDist + secondDist)
+}
+
+func colorRGB(c Color) rgb {
+	return rgb{R: float64(c.R), G: float64(c.G), B: float64(c.B)}
+}
+
+// hue returns the hue of c in degrees (0-360), or -1 if achromatic.
+func hue(c rgb) float64 {
+	maxC := math.Max(c.R, math.Max(c.G, c.B))
+	minC := math.Min(c.R, math.Min(c.G, c.B))
+	delta := maxC - minC
+	if delta < 10 {
+		return -1
+	}
+
+	var h float64
+	switch maxC {
+	case c.R:
+		h = math.Mod((c.G-c.B)/delta, 6)
+	case c.G:
+		h = (c.B-c.R)/delta + 2
+	default:
+		h = (c.R-c.G)/delta + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// hueDist returns the angular distance between two hues (0-180).
+func hueDist(h1, h2 float64) float64 {
+	d := math.Abs(h1 - h2)
+	if d > 180 {
+		return 360 - d
+	}
+	return d
+}
+
+func saturation(c rgb) float64 {
+	maxC := math.Max(c.R, math.Max(c.G, c.B))
+	if maxC == 0 {
+		return 0
+	}
+	return (maxC - math.Min(c.R, math.Min(c.G, c.B))) / maxC
+}
+
+func brightness(c rgb) float64 {
+	return (c.R + c.G + c.B) / 3
+}
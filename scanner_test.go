@@ -0,0 +1,273 @@
+package dotbeam
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/satindergrewal/dotbeam/sim"
+)
+
+// rotateImage rotates src clockwise by deg degrees about its centre using
+// nearest-neighbour sampling, filling uncovered pixels with the background.
+func rotateImage(src *image.RGBA, deg float64) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	cx := float64(b.Dx()) / 2
+	cy := float64(b.Dy()) / 2
+	rad := deg * math.Pi / 180
+	cosR, sinR := math.Cos(rad), math.Sin(rad)
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			// Inverse-map each destination pixel into the source.
+			dx := float64(x) + 0.5 - cx
+			dy := float64(y) + 0.5 - cy
+			sx := int(math.Floor(dx*cosR + dy*sinR + cx))
+			sy := int(math.Floor(-dx*sinR + dy*cosR + cy))
+			if sx < 0 || sx >= b.Dx() || sy < 0 || sy >= b.Dy() {
+				dst.SetRGBA(x, y, bgColor)
+				continue
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
+
+// mirrorImage flips src left to right, as a front camera or a reflection
+// would.
+func mirrorImage(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.SetRGBA(b.Dx()-1-x, y, src.RGBAAt(x, y))
+		}
+	}
+	return dst
+}
+
+func decodeRendered(t *testing.T, cfg Config, msg []byte, prepare func(*image.RGBA) image.Image) []byte {
+	t.Helper()
+	frames := mustEncode(t, NewEncoder(cfg), msg)
+	layout := NewLayout(cfg, 1, 1)
+	dec := NewDecoder(cfg)
+
+	for _, frame := range frames {
+		img := prepare(RenderFrame(frame, layout, 600, 600))
+		dots, _, err := DecodeImage(img, cfg)
+		if err != nil {
+			t.Fatalf("frame %d: DecodeImage error: %v", frame.Index, err)
+		}
+		if _, err := dec.AddFrame(dots); err != nil {
+			t.Fatalf("frame %d: AddFrame error: %v", frame.Index, err)
+		}
+	}
+
+	data, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	return data
+}
+
+func TestDecodeImageRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	msg := []byte("decoded straight from pixels")
+
+	got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image { return img })
+	if !bytes.HasPrefix(got, msg) {
+		t.Fatalf("round-trip mismatch:\n got: %q\nwant prefix: %q", got, msg)
+	}
+}
+
+func TestDecodeImageTransform(t *testing.T) {
+	cfg := DefaultConfig()
+	frames := mustEncode(t, NewEncoder(cfg), []byte("transform"))
+	img := RenderFrame(frames[0], NewLayout(cfg, 1, 1), 600, 600)
+
+	_, tr, err := DecodeImage(img, cfg)
+	if err != nil {
+		t.Fatalf("DecodeImage error: %v", err)
+	}
+
+	wantScale := 300 * 0.95
+	if math.Abs(tr.CenterX-300) > 5 || math.Abs(tr.CenterY-300) > 5 {
+		t.Errorf("center = (%.1f, %.1f), want ~(300, 300)", tr.CenterX, tr.CenterY)
+	}
+	if math.Abs(tr.Scale-wantScale)/wantScale > 0.03 {
+		t.Errorf("scale = %.1f, want ~%.1f", tr.Scale, wantScale)
+	}
+	if math.Abs(tr.Rotation) > 0.03 {
+		t.Errorf("rotation = %.3f rad, want ~0", tr.Rotation)
+	}
+}
+
+func TestDecodeImageRotated(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	msg := []byte("tilted phone")
+
+	for _, deg := range []float64{-40, -15, 25, 50} {
+		got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image {
+			return rotateImage(img, deg)
+		})
+		if !bytes.HasPrefix(got, msg) {
+			t.Fatalf("rotation %.0f°: round-trip mismatch: %q", deg, got)
+		}
+	}
+}
+
+func TestDecodeImageUpsideDown(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	msg := []byte("the cue names the top anchor")
+
+	// Beyond ±60° the bottommost anchor is no longer the 270° one.
+	for _, deg := range []float64{100, 180, -135} {
+		got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image {
+			return rotateImage(img, deg)
+		})
+		if !bytes.HasPrefix(got, msg) {
+			t.Fatalf("rotation %.0f°: round-trip mismatch: %q", deg, got)
+		}
+	}
+}
+
+func TestDecodeImageTilted(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	msg := []byte("laptop on the desk")
+
+	// An axis of 90° tips the top away; 0° turns the right edge away.
+	for _, tc := range []struct {
+		deg, axis float64
+	}{{40, 90}, {-40, 90}, {40, 0}, {-40, 0}, {30, 0}} {
+		ch := sim.Channel{Tilt: tc.deg, TiltAxis: tc.axis, Distance: 2, Rotation: 20}
+		got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image {
+			return ch.Apply(img)
+		})
+		if !bytes.HasPrefix(got, msg) {
+			t.Fatalf("tilt %.0f° about %.0f°: round-trip mismatch: %q", tc.deg, tc.axis, got)
+		}
+	}
+}
+
+func TestDecodeImageSimulated(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	msg := []byte("through the camera")
+
+	channels := map[string]sim.Channel{
+		"perspective":  {Tilt: 35, TiltAxis: 30},
+		"rotation":     {Rotation: 70},
+		"gaussian":     {Blur: 2},
+		"motion":       {MotionBlur: 8, MotionAngle: 30},
+		"noise":        {Seed: 1, Noise: 12},
+		"gamma":        {Gamma: 1.4},
+		"white":        {WhiteBalance: [3]float64{1.15, 1, 0.85}},
+		"jpeg":         {JPEGQuality: 40},
+		"moire":        {Seed: 2, Moire: 0.3},
+		"vignette":     {Vignette: 0.5},
+		"overexposed":  {Exposure: 1.2},
+		"underexposed": {Exposure: 0.8},
+	}
+	for seed := uint64(1); seed <= 8; seed++ {
+		channels[fmt.Sprintf("random %d", seed)] = sim.Random(seed, 0.5)
+	}
+
+	for name, ch := range channels {
+		t.Run(name, func(t *testing.T) {
+			got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image {
+				return ch.Apply(img)
+			})
+			if !bytes.HasPrefix(got, msg) {
+				t.Fatalf("round-trip mismatch: %q", got)
+			}
+		})
+	}
+}
+
+func TestDecodeImageMirrored(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	msg := []byte("selfie camera")
+
+	for _, deg := range []float64{0, 35, 160} {
+		got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image {
+			return rotateImage(mirrorImage(img), deg)
+		})
+		if !bytes.HasPrefix(got, msg) {
+			t.Fatalf("mirrored, rotation %.0f°: round-trip mismatch: %q", deg, got)
+		}
+	}
+
+	frames := mustEncode(t, NewEncoder(cfg), msg)
+	img := RenderFrame(frames[0], NewLayout(cfg, 1, 1), 600, 600)
+	if _, tr, err := DecodeImage(img, cfg); err != nil || tr.Mirrored {
+		t.Errorf("DecodeImage(plain) Mirrored = %v, err = %v; want false", tr.Mirrored, err)
+	}
+	if _, tr, err := DecodeImage(mirrorImage(img), cfg); err != nil || !tr.Mirrored {
+		t.Errorf("DecodeImage(mirrored) Mirrored = %v, err = %v; want true", tr.Mirrored, err)
+	}
+}
+
+func TestDecodeImageOffsetNonRGBA(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Checksum = true
+	msg := []byte("screenshot")
+
+	got := decodeRendered(t, cfg, msg, func(img *image.RGBA) image.Image {
+		// Paste the pattern off-centre into a larger NRGBA screenshot
+		// with a non-zero origin.
+		canvas := image.NewNRGBA(image.Rect(50, 50, 1050, 850))
+		draw.Draw(canvas, canvas.Bounds(), &image.Uniform{bgColor}, image.Point{}, draw.Src)
+		draw.Draw(canvas, image.Rect(400, 150, 1000, 750), img, image.Point{}, draw.Src)
+		return canvas
+	})
+	if !bytes.HasPrefix(got, msg) {
+		t.Fatalf("round-trip mismatch: %q", got)
+	}
+}
+
+func TestDecodeImageNoAnchors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{R: 40, G: 40, B: 40, A: 255}}, image.Point{}, draw.Src)
+
+	if _, _, err := DecodeImage(img, DefaultConfig()); err != ErrNoAnchors {
+		t.Fatalf("DecodeImage(blank) error = %v, want ErrNoAnchors", err)
+	}
+}
+
+func TestMatchColorPalette(t *testing.T) {
+	for i, c := range DefaultColors {
+		if got, _ := matchColor(colorRGB(c), Palette8); got != uint8(i) {
+			t.Errorf("matchColor(%s) = %d, want %d", c.Hex(), got, i)
+		}
+		// Half exposure keeps the hue.
+		dim := rgb{R: float64(c.R) / 2, G: float64(c.G) / 2, B: float64(c.B) / 2}
+		if got, _ := matchColor(dim, Palette8); got != uint8(i) {
+			t.Errorf("matchColor(dim %s) = %d, want %d", c.Hex(), got, i)
+		}
+	}
+}
+
+func TestMatchColorConfidence(t *testing.T) {
+	for _, c := range DefaultColors {
+		if _, conf := matchColor(colorRGB(c), Palette8); conf != 1 {
+			t.Errorf("matchColor(%s) confidence = %v, want 1", c.Hex(), conf)
+		}
+	}
+
+	// Halfway between Red and Orange in hue is a near tie.
+	mid := rgb{R: 255, G: 0x68, B: 0x22}
+	if _, conf := matchColor(mid, Palette8); conf < 0.5 || conf > 0.6 {
+		t.Errorf("matchColor(red/orange midpoint) confidence = %v, want about 0.5", conf)
+	}
+}
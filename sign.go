@@ -0,0 +1,82 @@
+package dotbeam
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Transfer signing.
+//
+// A signed transfer carries the signer's key fingerprint in the preamble
+// and a 64-byte Ed25519 signature right after the payload. The signature
+// covers the preamble and payload exactly as sent (ciphertext, when
+// encrypted), so a receiver can check the sender before decrypting.
+
+// fingerprintSize is the number of SHA-256 bytes kept as a key fingerprint.
+const fingerprintSize = 8
+
+// Fingerprint returns the short hex identity of a public key, as reported
+// in Metadata.Signer.
+func Fingerprint(pub ed25519.PublicKey) string {
+	return hex.EncodeToString(fingerprint(pub))
+}
+
+func fingerprint(pub ed25519.PublicKey) []byte {
+	sum := sha256.Sum256(pub)
+	return sum[:fingerprintSize]
+}
+
+// Sign signs subsequent transfers with priv. A nil key disables signing.
+// Requires Config.Preamble.
+func (e *Encoder) Sign(priv ed25519.PrivateKey) {
+	e.signKey = priv
+}
+
+// prepareSignature marks p as signed and records the signer fingerprint.
+func (e *Encoder) prepareSignature(p *preamble) {
+	pub := e.signKey.Public().(ed25519.PublicKey)
+	p.flags |= flagSigned
+	p.signer = fingerprint(pub)
+	p.Signer = hex.EncodeToString(p.signer)
+}
+
+// appendSignature appends the signature of stream (preamble + payload).
+func (e *Encoder) appendSignature(stream []byte) []byte {
+	return append(stream, ed25519.Sign(e.signKey, stream)...)
+}
+
+// trailerSize returns the number of bytes that follow the payload.
+func (p preamble) trailerSize() int {
+	if p.flags&flagSigned != 0 {
+		return ed25519.SignatureSize
+	}
+	return 0
+}
+
+// Verify checks that the completed transfer was signed by pub. It returns
+// ErrUnsigned for unsigned transfers and ErrBadSignature if the signature
+// does not match. Requires Config.Preamble.
+func (d *Decoder) Verify(pub ed25519.PublicKey) error {
+	result, err := d.transfer()
+	if err != nil {
+		return err
+	}
+	if !d.config.Preamble {
+		return ErrUnsigned
+	}
+	p, n, err := parseTransfer(result)
+	if err != nil {
+		return err
+	}
+	if p.flags&flagSigned == 0 {
+		return ErrUnsigned
+	}
+
+	end := n + p.Length
+	sig := result[end : end+ed25519.SignatureSize]
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, result[:end], sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
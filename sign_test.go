@@ -0,0 +1,118 @@
+package dotbeam
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+)
+
+func testKey(b byte) (ed25519.PublicKey, ed25519.PrivateKey) {
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{b}, ed25519.SeedSize))
+	return priv.Public().(ed25519.PublicKey), priv
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, priv := testKey(1)
+	otherPub, _ := testKey(2)
+
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.Sign(priv)
+	data := []byte("bc1qexampleaddress")
+	frames := mustEncode(t, enc, data)
+
+	dec := NewDecoder(config)
+	got, err := decodeAll(dec, frames)
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("round-trip failed:\n got: %q\nwant: %q", got, data)
+	}
+
+	meta, _ := dec.Metadata()
+	if meta.Signer != Fingerprint(pub) {
+		t.Errorf("Signer = %q, want %q", meta.Signer, Fingerprint(pub))
+	}
+	if err := dec.Verify(pub); err != nil {
+		t.Errorf("Verify(signer) = %v, want nil", err)
+	}
+	if err := dec.Verify(otherPub); err != ErrBadSignature {
+		t.Errorf("Verify(other key) = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestSignUnsigned(t *testing.T) {
+	pub, _ := testKey(1)
+	config := DefaultConfig()
+	config.Preamble = true
+	dec := NewDecoder(config)
+	decodeAll(dec, mustEncode(t, NewEncoder(config), []byte("anonymous")))
+	if err := dec.Verify(pub); err != ErrUnsigned {
+		t.Errorf("Verify on unsigned transfer = %v, want ErrUnsigned", err)
+	}
+}
+
+func TestSignSwappedPayload(t *testing.T) {
+	pub, priv := testKey(1)
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.Sign(priv)
+	stream, err := enc.transferBytes([]byte("pay to ALICE"))
+	if err != nil {
+		t.Fatalf("transferBytes error: %v", err)
+	}
+
+	// Someone re-renders the constellation with their own address.
+	i := bytes.Index(stream, []byte("ALICE"))
+	copy(stream[i:], "MALLO")
+
+	dec := NewDecoder(config)
+	decodeAll(dec, mustEncode(t, NewEncoder(DefaultConfig()), stream))
+	if err := dec.Verify(pub); err != ErrBadSignature {
+		t.Errorf("Verify on swapped payload = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestSignEncrypted(t *testing.T) {
+	pub, priv := testKey(3)
+	config := DefaultConfig()
+	config.Preamble = true
+	config.UseFountain = true
+	enc := NewEncoder(config)
+	enc.Sign(priv)
+	enc.SetKey(bytes.Repeat([]byte{7}, 32))
+	data := bytes.Repeat([]byte("invite-code "), 5)
+	frames := mustEncode(t, enc, data)
+
+	// The signature covers the ciphertext, so it checks out without the key.
+	dec := NewDecoder(config)
+	for _, f := range frames {
+		if done, _ := dec.AddFrame(f.Dots); done {
+			break
+		}
+	}
+	if err := dec.Verify(pub); err != nil {
+		t.Errorf("Verify without key = %v, want nil", err)
+	}
+
+	dec.SetKey(bytes.Repeat([]byte{7}, 32))
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("round-trip failed")
+	}
+}
+
+func TestSignRequiresPreamble(t *testing.T) {
+	_, priv := testKey(1)
+	enc := NewEncoder(DefaultConfig())
+	enc.Sign(priv)
+	if _, err := enc.Encode([]byte("x")); err != ErrInvalidConfig {
+		t.Errorf("Encode without preamble = %v, want ErrInvalidConfig", err)
+	}
+}
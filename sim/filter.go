@@ -0,0 +1,183 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// gaussianBlur blurs f with a Gaussian of standard deviation sigma pixels,
+// as a horizontal and then a vertical pass.
+func (f *frame) gaussianBlur(sigma float64) *frame {
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := range kernel {
+		x := float64(i - radius)
+		kernel[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return f.convolve(kernel, 1, 0).convolve(kernel, 0, 1)
+}
+
+// convolve applies a 1-D kernel along direction (dx, dy), clamping at the
+// edges.
+func (f *frame) convolve(kernel []float64, dx, dy int) *frame {
+	taps := make([]tap, len(kernel))
+	radius := len(kernel) / 2
+	for i, k := range kernel {
+		taps[i] = tap{dx: (i - radius) * dx, dy: (i - radius) * dy, w: k}
+	}
+	return f.filter(taps)
+}
+
+// motionBlur averages f along a streak of length pixels at angle degrees
+// clockwise from horizontal, as a camera moving during exposure would.
+func (f *frame) motionBlur(length, angle float64) *frame {
+	// Spread each point of the streak bilinearly over its four nearest
+	// pixels, merging taps that land on the same one.
+	steps := max(2, int(math.Ceil(length))+1)
+	ux := math.Cos(angle*math.Pi/180) * length / float64(steps-1)
+	uy := math.Sin(angle*math.Pi/180) * length / float64(steps-1)
+	weights := map[[2]int]float64{}
+	for i := 0; i < steps; i++ {
+		t := float64(i) - float64(steps-1)/2
+		x, y := t*ux, t*uy
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		w := 1 / float64(steps)
+		weights[[2]int{int(x0), int(y0)}] += w * (1 - fx) * (1 - fy)
+		weights[[2]int{int(x0) + 1, int(y0)}] += w * fx * (1 - fy)
+		weights[[2]int{int(x0), int(y0) + 1}] += w * (1 - fx) * fy
+		weights[[2]int{int(x0) + 1, int(y0) + 1}] += w * fx * fy
+	}
+	var taps []tap
+	for off, w := range weights {
+		if w > 0 {
+			taps = append(taps, tap{dx: off[0], dy: off[1], w: w})
+		}
+	}
+	return f.filter(taps)
+}
+
+// tap is one weighted pixel offset of a filter kernel.
+type tap struct {
+	dx, dy int
+	w      float64
+}
+
+// filter applies a sparse kernel, clamping coordinates at the edges.
+func (f *frame) filter(taps []tap) *frame {
+	out := &frame{w: f.w, h: f.h, pix: make([]float64, len(f.pix))}
+	reach := 0
+	for _, t := range taps {
+		reach = max(reach, abs(t.dx), abs(t.dy))
+	}
+	for y := 0; y < f.h; y++ {
+		inner := y >= reach && y < f.h-reach
+		for x := 0; x < f.w; x++ {
+			o := 3 * (y*f.w + x)
+			if inner && x >= reach && x < f.w-reach {
+				// No clamping needed away from the edges.
+				for _, t := range taps {
+					i := o + 3*(t.dy*f.w+t.dx)
+					out.pix[o] += t.w * f.pix[i]
+					out.pix[o+1] += t.w * f.pix[i+1]
+					out.pix[o+2] += t.w * f.pix[i+2]
+				}
+				continue
+			}
+			for _, t := range taps {
+				sx := min(f.w-1, max(0, x+t.dx))
+				sy := min(f.h-1, max(0, y+t.dy))
+				i := 3 * (sy*f.w + sx)
+				out.pix[o] += t.w * f.pix[i]
+				out.pix[o+1] += t.w * f.pix[i+1]
+				out.pix[o+2] += t.w * f.pix[i+2]
+			}
+		}
+	}
+	return out
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// moire overlays interference fringes between the screen's pixel grid and
+// the sensor's: a sinusoidal brightness ripple of the given contrast and
+// period, at a random angle and phase.
+func (f *frame) moire(contrast, period float64, rng *rand.Rand) {
+	angle := math.Pi * rng.Float64()
+	phase := 2 * math.Pi * rng.Float64()
+	ca, sa := math.Cos(angle), math.Sin(angle)
+	for y := 0; y < f.h; y++ {
+		for x := 0; x < f.w; x++ {
+			along := float64(x)*ca + float64(y)*sa
+			g := 1 - contrast*(0.5+0.5*math.Cos(2*math.Pi*along/period+phase))
+			f.scale(x, y, [3]float64{g, g, g})
+		}
+	}
+}
+
+// vignette darkens f towards the corners, losing strength of the
+// brightness there.
+func (f *frame) vignette(strength float64) {
+	cx, cy := float64(f.w)/2, float64(f.h)/2
+	r2 := cx*cx + cy*cy
+	for y := 0; y < f.h; y++ {
+		for x := 0; x < f.w; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			g := 1 - strength*(dx*dx+dy*dy)/r2
+			f.scale(x, y, [3]float64{g, g, g})
+		}
+	}
+}
+
+// gain multiplies every pixel by exposure and the per-channel white
+// balance.
+func (f *frame) gain(exposure float64, wb [3]float64) {
+	for i := range f.pix {
+		f.pix[i] *= exposure * wb[i%3]
+	}
+}
+
+// noise adds Gaussian sensor noise of the given standard deviation to each
+// channel independently.
+func (f *frame) noise(sigma float64, rng *rand.Rand) {
+	for i := range f.pix {
+		f.pix[i] += sigma * rng.NormFloat64()
+	}
+}
+
+// clip saturates f to the sensor's 0-255 range.
+func (f *frame) clip() {
+	for i, v := range f.pix {
+		f.pix[i] = min(255, max(0, v))
+	}
+}
+
+// gamma applies the tone curve 255·(v/255)^g to the clipped frame, from a
+// table at the 12-bit precision of a typical camera's processing.
+func (f *frame) gamma(g float64) {
+	const levels = 4096
+	var curve [levels]float64
+	for i := range curve {
+		curve[i] = 255 * math.Pow(float64(i)/(levels-1), g)
+	}
+	for i, v := range f.pix {
+		f.pix[i] = curve[int(math.Round(v/255*(levels-1)))]
+	}
+}
+
+func (f *frame) scale(x, y int, g [3]float64) {
+	i := 3 * (y*f.w + x)
+	f.pix[i] *= g[0]
+	f.pix[i+1] *= g[1]
+	f.pix[i+2] *= g[2]
+}
@@ -0,0 +1,57 @@
+package sim
+
+import "math"
+
+// warp views f as a screen turned tilt degrees about an axis through its
+// centre (axis degrees from vertical), through a pinhole camera dist image
+// widths away, then rolled rot degrees clockwise.
+func (f *frame) warp(tilt, axis, dist, rot float64) *frame {
+	out := &frame{w: f.w, h: f.h, pix: make([]float64, len(f.pix))}
+	cx, cy := float64(f.w)/2, float64(f.h)/2
+	d := dist * float64(f.w)
+	ct, st := math.Cos(tilt*math.Pi/180), math.Sin(tilt*math.Pi/180)
+	ca, sa := math.Cos(axis*math.Pi/180), math.Sin(axis*math.Pi/180)
+	cr, sr := math.Cos(rot*math.Pi/180), math.Sin(rot*math.Pi/180)
+	bg := f.background()
+
+	for y := 0; y < f.h; y++ {
+		for x := 0; x < f.w; x++ {
+			// Undo the roll.
+			px := float64(x) + 0.5 - cx
+			py := float64(y) + 0.5 - cy
+			px, py = px*cr+py*sr, -px*sr+py*cr
+
+			// Turn the tilt axis vertical, intersect the viewing ray with
+			// the tilted screen, and turn back.
+			qx, qy := px*ca+py*sa, -px*sa+py*ca
+			den := d*ct - qx*st
+			c := bg
+			if den > 0 {
+				u := qx * d / den
+				v := qy * (d + u*st) / d
+				c = f.bilinear(u*ca-v*sa+cx-0.5, u*sa+v*ca+cy-0.5, bg)
+			}
+			copy(out.pix[3*(y*f.w+x):], c[:])
+		}
+	}
+	return out
+}
+
+// bilinear samples f at pixel-centre coordinates (x, y), returning bg
+// outside the image.
+func (f *frame) bilinear(x, y float64, bg [3]float64) [3]float64 {
+	if x < 0 || y < 0 || x > float64(f.w-1) || y > float64(f.h-1) {
+		return bg
+	}
+	x0, y0 := int(x), int(y)
+	x1, y1 := min(x0+1, f.w-1), min(y0+1, f.h-1)
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	var c [3]float64
+	for ch := range c {
+		top := f.pix[3*(y0*f.w+x0)+ch]*(1-fx) + f.pix[3*(y0*f.w+x1)+ch]*fx
+		bottom := f.pix[3*(y1*f.w+x0)+ch]*(1-fx) + f.pix[3*(y1*f.w+x1)+ch]*fx
+		c[ch] = top*(1-fy) + bottom*fy
+	}
+	return c
+}
@@ -0,0 +1,192 @@
+// Package sim simulates the optical channel between a screen and a phone
+// camera, so image decoders can be tested without either.
+//
+// A Channel describes a capture: how the screen is angled, how the lens
+// and hand blur it, how the sensor exposes and colors it, and how the
+// result is compressed. Apply runs an image through those stages in the
+// order light meets them:
+//
+//	moiré → perspective and rotation → Gaussian blur → motion blur →
+//	vignetting → exposure → white balance → sensor noise → clipping →
+//	gamma → JPEG
+//
+// Every field's zero value disables its stage, so a Channel can name just
+// the distortions a test cares about. All randomness (sensor noise and the
+// moiré pattern's angle and phase) comes from Seed, so the same Channel
+// always produces the same image.
+package sim
+
+import (
+	"bytes"
+	"cmp"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"math/rand/v2"
+)
+
+// Channel is a set of capture distortions.
+type Channel struct {
+	Seed uint64 // drives noise and the moiré pattern
+
+	// Geometry.
+	Tilt     float64 // degrees the screen is turned away from the camera
+	TiltAxis float64 // direction of the axis it turns about, degrees from vertical
+	Distance float64 // camera distance in image widths; 0 means 2
+	Rotation float64 // degrees clockwise
+
+	// Optics.
+	Blur        float64 // Gaussian blur standard deviation in pixels
+	MotionBlur  float64 // motion streak length in pixels
+	MotionAngle float64 // motion streak direction, degrees clockwise from horizontal
+
+	// Screen against sensor grid.
+	Moire       float64 // fringe contrast, 0-1
+	MoirePeriod float64 // fringe period in pixels; 0 means 7
+
+	// Sensor.
+	Vignette     float64    // brightness lost in the corners, 0-1
+	Exposure     float64    // brightness gain; 0 means 1, highlights clip at 255
+	WhiteBalance [3]float64 // R, G, B gains; all zero means neutral
+	Noise        float64    // noise standard deviation in 8-bit levels
+	Gamma        float64    // tone curve exponent; 0 means 1, above 1 darkens
+
+	// Encoding.
+	JPEGQuality int // recompress at this quality, 1-100; 0 skips
+}
+
+// Apply returns img as captured through c. The result has the same size as
+// img, with its origin at (0, 0).
+func (c Channel) Apply(img image.Image) *image.RGBA {
+	rng := rand.New(rand.NewPCG(c.Seed, c.Seed^0x9e3779b97f4a7c15))
+	f := newFrame(img)
+
+	if c.Moire > 0 {
+		f.moire(c.Moire, cmp.Or(c.MoirePeriod, 7), rng)
+	}
+	if c.Tilt != 0 || c.Rotation != 0 {
+		f = f.warp(c.Tilt, c.TiltAxis, cmp.Or(c.Distance, 2), c.Rotation)
+	}
+	if c.Blur > 0 {
+		f = f.gaussianBlur(c.Blur)
+	}
+	if c.MotionBlur > 0 {
+		f = f.motionBlur(c.MotionBlur, c.MotionAngle)
+	}
+	if c.Vignette > 0 {
+		f.vignette(c.Vignette)
+	}
+	gain := [3]float64{1, 1, 1}
+	if c.WhiteBalance != ([3]float64{}) {
+		gain = c.WhiteBalance
+	}
+	f.gain(cmp.Or(c.Exposure, 1), gain)
+	if c.Noise > 0 {
+		f.noise(c.Noise, rng)
+	}
+	f.clip()
+	if c.Gamma > 0 && c.Gamma != 1 {
+		f.gamma(c.Gamma)
+	}
+
+	out := f.rgba()
+	if c.JPEGQuality > 0 {
+		out = recompress(out, c.JPEGQuality)
+	}
+	return out
+}
+
+// Random returns a Channel with every distortion drawn at random from the
+// given seed. strength scales them all, from 0 (a clean capture) through 1
+// (a handheld phone in poor light) and beyond.
+func Random(seed uint64, strength float64) Channel {
+	rng := rand.New(rand.NewPCG(seed, ^seed))
+	between := func(lo, hi float64) float64 {
+		return strength * (lo + (hi-lo)*rng.Float64())
+	}
+	signed := func(hi float64) float64 {
+		return strength * hi * (2*rng.Float64() - 1)
+	}
+	c := Channel{
+		Seed:        seed,
+		Tilt:        signed(30),
+		TiltAxis:    360 * rng.Float64(),
+		Rotation:    signed(45),
+		Blur:        between(0.3, 1.5),
+		MotionBlur:  between(0, 4),
+		MotionAngle: 180 * rng.Float64(),
+		Moire:       between(0, 0.15),
+		Vignette:    between(0.1, 0.4),
+		Exposure:    1 + signed(0.3),
+		WhiteBalance: [3]float64{
+			1 + signed(0.15),
+			1 + signed(0.1),
+			1 + signed(0.15),
+		},
+		Noise: between(2, 8),
+		Gamma: 1 + signed(0.3),
+	}
+	if strength > 0 {
+		c.JPEGQuality = 100 - int(between(5, 35))
+	}
+	return c
+}
+
+// frame is a floating-point RGB image, unclamped between stages.
+type frame struct {
+	w, h int
+	pix  []float64 // R, G, B per pixel, row by row
+}
+
+func newFrame(img image.Image) *frame {
+	b := img.Bounds()
+	rgba, ok := img.(*image.RGBA)
+	if !ok || b.Min != (image.Point{}) || rgba.Stride != 4*b.Dx() {
+		rgba = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	}
+
+	f := &frame{w: b.Dx(), h: b.Dy(), pix: make([]float64, 3*b.Dx()*b.Dy())}
+	for i := 0; i < f.w*f.h; i++ {
+		f.pix[3*i] = float64(rgba.Pix[4*i])
+		f.pix[3*i+1] = float64(rgba.Pix[4*i+1])
+		f.pix[3*i+2] = float64(rgba.Pix[4*i+2])
+	}
+	return f
+}
+
+func (f *frame) rgba() *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, f.w, f.h))
+	for i := 0; i < f.w*f.h; i++ {
+		for ch := 0; ch < 3; ch++ {
+			out.Pix[4*i+ch] = uint8(math.Round(min(255, max(0, f.pix[3*i+ch]))))
+		}
+		out.Pix[4*i+3] = 0xff
+	}
+	return out
+}
+
+// recompress encodes img as a JPEG at the given quality and decodes it.
+func recompress(img *image.RGBA, quality int) *image.RGBA {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: min(100, quality)}); err != nil {
+		return img
+	}
+	decoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		return img
+	}
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+	return out
+}
+
+// background is the color warped-in areas are filled with: the image's
+// top-left pixel, which for a rendered frame is the background.
+func (f *frame) background() [3]float64 {
+	if len(f.pix) == 0 {
+		return [3]float64{}
+	}
+	return [3]float64{f.pix[0], f.pix[1], f.pix[2]}
+}
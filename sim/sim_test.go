@@ -0,0 +1,110 @@
+package sim
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// testImage returns a dark square canvas with a white square of half-width
+// r centred on (x, y).
+func testImage(size, x, y, r int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{R: 10, G: 10, B: 26, A: 255}}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(x-r, y-r, x+r, y+r), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestApplyZeroIsIdentity(t *testing.T) {
+	img := testImage(64, 20, 40, 5)
+	if got := (Channel{}).Apply(img); !bytes.Equal(got.Pix, img.Pix) {
+		t.Error("zero Channel changed the image")
+	}
+}
+
+func TestApplyDeterministic(t *testing.T) {
+	img := testImage(96, 48, 48, 10)
+	a := Random(7, 1).Apply(img)
+	b := Random(7, 1).Apply(img)
+	if !bytes.Equal(a.Pix, b.Pix) {
+		t.Error("same Channel produced different images")
+	}
+	if c := Random(8, 1).Apply(img); bytes.Equal(a.Pix, c.Pix) {
+		t.Error("different seeds produced identical images")
+	}
+}
+
+func TestApplyNonZeroOrigin(t *testing.T) {
+	img := testImage(64, 32, 32, 6).SubImage(image.Rect(16, 16, 48, 48))
+	got := Channel{Blur: 1}.Apply(img)
+	if got.Bounds() != image.Rect(0, 0, 32, 32) {
+		t.Fatalf("bounds = %v, want 32×32 at the origin", got.Bounds())
+	}
+	if c := got.RGBAAt(16, 16); c.R < 200 {
+		t.Errorf("centre = %v, want white", c)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	// A quarter turn clockwise moves a spot right of centre to below it.
+	got := Channel{Rotation: 90}.Apply(testImage(100, 80, 50, 4))
+	if c := got.RGBAAt(50, 80); c.R < 200 {
+		t.Errorf("rotated spot = %v, want white", c)
+	}
+	if c := got.RGBAAt(80, 50); c.R > 50 {
+		t.Errorf("original spot = %v, want background", c)
+	}
+}
+
+func TestTiltForeshortens(t *testing.T) {
+	// Turning the screen about its vertical axis narrows it but keeps the
+	// centre in place.
+	got := Channel{Tilt: 60}.Apply(testImage(100, 50, 50, 30))
+	if c := got.RGBAAt(50, 50); c.R < 200 {
+		t.Errorf("centre = %v, want white", c)
+	}
+	if c := got.RGBAAt(24, 50); c.R > 50 {
+		t.Errorf("left edge = %v, want background after foreshortening", c)
+	}
+	if c := got.RGBAAt(50, 24); c.R < 200 {
+		t.Errorf("top edge = %v, want white", c)
+	}
+}
+
+func TestBlurSpreads(t *testing.T) {
+	img := testImage(32, 16, 16, 1)
+	for _, ch := range []Channel{{Blur: 2}, {MotionBlur: 8}} {
+		got := ch.Apply(img)
+		if c := got.RGBAAt(16, 16); c.R > 200 {
+			t.Errorf("%+v: centre = %v, want dimmed", ch, c)
+		}
+		if c := got.RGBAAt(18, 16); c.R < 20 {
+			t.Errorf("%+v: neighbour = %v, want lit", ch, c)
+		}
+	}
+}
+
+func TestExposureClips(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 200, G: 100, B: 20, A: 255})
+
+	got := Channel{Exposure: 2}.Apply(img).RGBAAt(0, 0)
+	if want := (color.RGBA{R: 255, G: 200, B: 40, A: 255}); got != want {
+		t.Errorf("Exposure 2: got %v, want %v", got, want)
+	}
+	got = Channel{WhiteBalance: [3]float64{1, 1, 0.5}}.Apply(img).RGBAAt(0, 0)
+	if want := (color.RGBA{R: 200, G: 100, B: 10, A: 255}); got != want {
+		t.Errorf("WhiteBalance: got %v, want %v", got, want)
+	}
+}
+
+func TestNoiseAndJPEG(t *testing.T) {
+	img := testImage(64, 32, 32, 8)
+	for _, ch := range []Channel{{Seed: 3, Noise: 5}, {JPEGQuality: 30}, {Seed: 3, Moire: 0.3}, {Vignette: 0.5}} {
+		if got := ch.Apply(img); bytes.Equal(got.Pix, img.Pix) {
+			t.Errorf("%+v left the image unchanged", ch)
+		}
+	}
+}
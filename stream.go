@@ -0,0 +1,251 @@
+package dotbeam
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"iter"
+)
+
+// EncodeStream returns the frames for the bytes read from r, reading one
+// frame's worth of input at a time. The frame total must be known before
+// the first frame, so r is read lazily only when its size is known (it has
+// a Len method, like bytes.Reader, or is a regular file) and the payload
+// is not compressed, encrypted or signed; otherwise r is read in full
+// first. With Config.UseFountain set, the sequence is an endless stream of
+// LT symbols; stop ranging once the receiver is done.
+func (e *Encoder) EncodeStream(r io.Reader) iter.Seq2[Frame, error] {
+	return func(yield func(Frame, error) bool) {
+		if err := e.config.validate(); err != nil {
+			yield(Frame{}, err)
+			return
+		}
+
+		size, known := streamSize(r)
+		if !known || e.config.UseFountain || e.wholePayload() {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				yield(Frame{}, err)
+				return
+			}
+			if e.config.UseFountain {
+				e.streamFountain(data, yield)
+				return
+			}
+			if data, err = e.transferBytes(data); err != nil {
+				yield(Frame{}, err)
+				return
+			}
+			r, size = bytes.NewReader(data), len(data)
+		} else if e.config.Preamble {
+			p := preamble{Metadata: e.meta}
+			p.Length, p.OriginalLength = size, size
+			head := marshalPreamble(p)
+			r, size = io.MultiReader(bytes.NewReader(head), r), size+len(head)
+		}
+
+		bytesPerFrame := e.config.BytesPerFrame()
+		totalFrames := (size + bytesPerFrame - 1) / bytesPerFrame
+		if totalFrames > e.config.MaxFrames() {
+			yield(Frame{}, ErrTooLarge)
+			return
+		}
+		e.session = newSessionID()
+
+		for i := 0; i < totalFrames; i++ {
+			chunk := make([]byte, min(bytesPerFrame, size-i*bytesPerFrame))
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				yield(Frame{}, err)
+				return
+			}
+			if !yield(e.frame(i, totalFrames, chunk), nil) {
+				return
+			}
+		}
+	}
+}
+
+// streamFountain yields LT symbols for data until the consumer stops.
+func (e *Encoder) streamFountain(data []byte, yield func(Frame, error) bool) {
+	f, err := e.Fountain(data)
+	if err != nil {
+		yield(Frame{}, err)
+		return
+	}
+	if f == nil {
+		return
+	}
+	for yield(f.Next(), nil) {
+	}
+}
+
+// wholePayload reports whether transferBytes needs the complete payload:
+// compression, encryption and signing all work on the whole message.
+func (e *Encoder) wholePayload() bool {
+	return e.config.Compress || e.secret.enabled() || e.signKey != nil
+}
+
+// streamSize returns the number of bytes left in r, if it can tell.
+func streamSize(r io.Reader) (int, bool) {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return v.Len(), true
+	case interface {
+		Stat() (fs.FileInfo, error)
+		io.Seeker
+	}:
+		info, err := v.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		pos, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return int(info.Size() - pos), true
+	}
+	return 0, false
+}
+
+// streamWriter is the state of a streaming Decoder.
+type streamWriter struct {
+	w       io.Writer
+	next    int       // next frame (or source block) index to flush
+	pending []byte    // contiguous bytes not yet written
+	head    []byte    // raw preamble, once parsed
+	pre     *preamble // parsed preamble
+	left    int       // payload bytes still to write
+	done    bool      // whole payload written
+	err     error     // sticky write error
+}
+
+// NewStreamDecoder creates a decoder that writes recovered bytes to w as
+// soon as they are contiguous, and frees each frame once written, so
+// memory stays bounded by the frames that arrived out of order. Fountain
+// blocks are freed the same way; a later symbol that combines a freed
+// block can no longer be peeled and is dropped, which in tests costs at
+// most about a tenth more symbols for small K and nothing measurable
+// from K=500. With
+// Config.Preamble set, the preamble is stripped and exactly Length bytes
+// are written. Compressed, encrypted or signed payloads can only be
+// unwrapped whole, so those are buffered and written on completion.
+// Data returns ErrStreamed for stream decoders.
+func NewStreamDecoder(config Config, w io.Writer) *Decoder {
+	d := NewDecoder(config)
+	d.stream = &streamWriter{w: w}
+	return d
+}
+
+// flushed reports whether a frame index was already written and freed.
+func (d *Decoder) flushed(index int) bool {
+	return d.stream != nil && index < d.stream.next
+}
+
+// flush moves newly contiguous frames (or recovered fountain blocks) into
+// the pending buffer and writes what it can.
+func (d *Decoder) flush() error {
+	s := d.stream
+	if s.err != nil {
+		return s.err
+	}
+	for {
+		if d.fountain != nil {
+			if s.next >= d.fountain.k || d.fountain.blocks[s.next] == nil {
+				break
+			}
+			s.pending = append(s.pending, d.fountain.blocks[s.next]...)
+			d.fountain.release(s.next + 1)
+		} else {
+			payload, ok := d.frames[s.next]
+			if !ok {
+				break
+			}
+			s.pending = append(s.pending, payload...)
+			delete(d.frames, s.next)
+		}
+		s.next++
+	}
+	s.err = d.drain()
+	return s.err
+}
+
+// drain writes as much of the pending buffer as the preamble allows.
+func (d *Decoder) drain() error {
+	s := d.stream
+	if !d.config.Preamble {
+		_, err := s.w.Write(s.pending)
+		s.pending = s.pending[:0]
+		return err
+	}
+
+	if s.pre == nil {
+		p, n, err := parsePreamble(s.pending)
+		if err == ErrIncompleteData {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.pre = &p
+		s.head = append([]byte(nil), s.pending[:n]...)
+		s.pending = append([]byte(nil), s.pending[n:]...)
+		s.left = p.Length
+		if err := d.checkEncrypted(p); err != nil {
+			return err
+		}
+	}
+	if s.done {
+		return nil
+	}
+
+	if s.pre.flags != 0 {
+		// Compressed, encrypted or signed: unwrap the payload as a whole.
+		if len(s.pending) < s.pre.Length+s.pre.trailerSize() {
+			return nil
+		}
+		data, err := d.unwrap(*s.pre, s.head, s.pending[:s.pre.Length])
+		if err != nil {
+			return err
+		}
+		s.done = true
+		_, err = s.w.Write(data)
+		return err
+	}
+
+	n := min(len(s.pending), s.left)
+	_, err := s.w.Write(s.pending[:n])
+	s.left -= n
+	s.pending = s.pending[n:]
+	if s.left == 0 {
+		s.done = true
+		s.pending = nil
+	}
+	return err
+}
+
+// transfer returns the raw received transfer for signature checks. Stream
+// decoders keep it only for signed payloads, which are buffered whole. It
+// is available once every byte has arrived, even if the payload could not
+// be unwrapped, so an encrypted transfer can be verified without its key.
+func (d *Decoder) transfer() ([]byte, error) {
+	if d.stream == nil {
+		return d.assemble()
+	}
+	s := d.stream
+	if !d.config.Preamble {
+		return nil, ErrUnsigned
+	}
+	if s.pre == nil {
+		return nil, ErrIncompleteData
+	}
+	if s.pre.flags&flagSigned == 0 {
+		return nil, ErrUnsigned
+	}
+	if len(s.pending) < s.pre.Length+s.pre.trailerSize() {
+		return nil, ErrIncompleteData
+	}
+	return append(append([]byte(nil), s.head...), s.pending...), nil
+}
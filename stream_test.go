@@ -0,0 +1,321 @@
+package dotbeam
+
+import (
+	"bytes"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// collect gathers every frame from a stream, failing on error.
+func collect(t *testing.T, seq iter.Seq2[Frame, error]) []Frame {
+	t.Helper()
+	var frames []Frame
+	for f, err := range seq {
+		if err != nil {
+			t.Fatalf("stream error after %d frames: %v", len(frames), err)
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+func sameFrames(t *testing.T, got, want []Frame) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Index != want[i].Index || got[i].Total != want[i].Total ||
+			!bytes.Equal(got[i].Payload, want[i].Payload) {
+			t.Fatalf("frame %d differs: got %d/%d %q, want %d/%d %q", i,
+				got[i].Index, got[i].Total, got[i].Payload,
+				want[i].Index, want[i].Total, want[i].Payload)
+		}
+		for j := range want[i].Dots {
+			if got[i].Dots[j] != want[i].Dots[j] {
+				t.Fatalf("frame %d dot %d differs", i, j)
+			}
+		}
+	}
+}
+
+// countingReader hides any Len method and counts bytes read.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestEncodeStreamMatchesEncode(t *testing.T) {
+	data := bytes.Repeat([]byte("stream me "), 30)
+	withPreamble := DefaultConfig()
+	withPreamble.Preamble = true
+	compressed := withPreamble
+	compressed.Compress = true
+	for _, config := range []Config{DefaultConfig(), withPreamble, compressed} {
+		enc := NewEncoder(config)
+		want := mustEncode(t, enc, data)
+
+		sameFrames(t, collect(t, enc.EncodeStream(bytes.NewReader(data))), want)
+		sameFrames(t, collect(t, enc.EncodeStream(&countingReader{r: bytes.NewReader(data)})), want)
+	}
+}
+
+func TestEncodeStreamLazy(t *testing.T) {
+	config := DefaultConfig()
+	data := bytes.Repeat([]byte("L"), 20*100)
+
+	// A sized reader is consumed one frame at a time.
+	src := bytes.NewReader(data)
+	cr := &countingReader{r: src}
+	seen := 0
+	for _, err := range NewEncoder(config).EncodeStream(lenReader{r: cr, src: src}) {
+		if err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		if seen++; seen == 3 {
+			break
+		}
+	}
+	if cr.n > 3*config.BytesPerFrame() {
+		t.Errorf("read %d bytes for 3 frames, want at most %d", cr.n, 3*config.BytesPerFrame())
+	}
+}
+
+// lenReader reads through r but reports the remaining length of src.
+type lenReader struct {
+	r   io.Reader
+	src *bytes.Reader
+}
+
+func (l lenReader) Read(p []byte) (int, error) { return l.r.Read(p) }
+func (l lenReader) Len() int                   { return l.src.Len() }
+
+func TestEncodeStreamFile(t *testing.T) {
+	data := bytes.Repeat([]byte("file payload "), 25)
+	path := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	config := DefaultConfig()
+	config.Preamble = true
+	got, err := decodeAll(NewDecoder(config), collect(t, NewEncoder(config).EncodeStream(f)))
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("round-trip failed")
+	}
+}
+
+func TestEncodeStreamShortInput(t *testing.T) {
+	// Claims 100 bytes but delivers 30.
+	src := bytes.NewReader(make([]byte, 100))
+	r := lenReader{r: io.LimitReader(src, 30), src: src}
+	var last error
+	for _, err := range NewEncoder(DefaultConfig()).EncodeStream(r) {
+		last = err
+	}
+	if last != io.ErrUnexpectedEOF {
+		t.Errorf("short input: err = %v, want io.ErrUnexpectedEOF", last)
+	}
+}
+
+func TestEncodeStreamFountain(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	data := bytes.Repeat([]byte("endless "), 40)
+	dec := NewDecoder(config)
+
+	n := 0
+	for f, err := range NewEncoder(config).EncodeStream(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		n++
+		if done, _ := dec.AddFrame(f.Dots); done || n > 1000 {
+			break
+		}
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error after %d symbols: %v", n, err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatal("round-trip failed")
+	}
+}
+
+func TestStreamDecoderOutOfOrder(t *testing.T) {
+	config := DefaultConfig()
+	data := bytes.Repeat([]byte("0123456789"), 10) // 5 frames
+	frames := mustEncode(t, NewEncoder(config), data)
+
+	var out bytes.Buffer
+	dec := NewStreamDecoder(config, &out)
+
+	// Frames 1 and 2 wait for frame 0; then all three flush at once.
+	dec.AddFrame(frames[2].Dots)
+	dec.AddFrame(frames[1].Dots)
+	if out.Len() != 0 {
+		t.Fatalf("wrote %d bytes before frame 0 arrived", out.Len())
+	}
+	dec.AddFrame(frames[0].Dots)
+	if out.Len() != 3*config.BytesPerFrame() {
+		t.Fatalf("wrote %d bytes after frames 0-2, want %d", out.Len(), 3*config.BytesPerFrame())
+	}
+	if len(dec.frames) != 0 {
+		t.Errorf("%d written frames still held in memory", len(dec.frames))
+	}
+
+	// A repeat of a flushed frame must not count twice.
+	dec.AddFrame(frames[0].Dots)
+	if p := dec.Progress(); p != 3.0/5.0 {
+		t.Errorf("progress after duplicate = %f, want 0.6", p)
+	}
+
+	dec.AddFrame(frames[4].Dots)
+	done, err := dec.AddFrame(frames[3].Dots)
+	if err != nil || !done {
+		t.Fatalf("AddFrame = (%v, %v), want (true, nil)", done, err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), data) {
+		t.Fatal("streamed output mismatch")
+	}
+	if _, err := dec.Data(); err != ErrStreamed {
+		t.Errorf("Data() = %v, want ErrStreamed", err)
+	}
+}
+
+func TestStreamDecoderPreamble(t *testing.T) {
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.SetMetadata(Metadata{Filename: "notes.txt"})
+	data := append(bytes.Repeat([]byte("exact "), 20), 0, 0)
+	frames := mustEncode(t, enc, data)
+
+	var out bytes.Buffer
+	dec := NewStreamDecoder(config, &out)
+	for _, f := range frames {
+		dec.AddFrame(f.Dots)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("streamed %q, want %q", out.Bytes(), data)
+	}
+	if meta, err := dec.Metadata(); err != nil || meta.Filename != "notes.txt" {
+		t.Errorf("Metadata() = (%+v, %v)", meta, err)
+	}
+}
+
+func TestStreamDecoderWholePayload(t *testing.T) {
+	pub, priv := testKey(5)
+	config := DefaultConfig()
+	config.Preamble = true
+	config.Compress = true
+	enc := NewEncoder(config)
+	enc.Sign(priv)
+	data := bytes.Repeat([]byte(`{"k":"v"},`), 30)
+	frames := mustEncode(t, enc, data)
+
+	var out bytes.Buffer
+	dec := NewStreamDecoder(config, &out)
+	for i, f := range frames {
+		dec.AddFrame(f.Dots)
+		if i < len(frames)-1 && out.Len() != 0 {
+			t.Fatal("compressed payload written before the transfer completed")
+		}
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatal("streamed output mismatch")
+	}
+	if err := dec.Verify(pub); err != nil {
+		t.Errorf("Verify = %v, want nil", err)
+	}
+}
+
+func TestStreamDecoderFountain(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	config.Preamble = true
+	data := bytes.Repeat([]byte("fountain stream "), 20)
+
+	var out bytes.Buffer
+	dec := NewStreamDecoder(config, &out)
+	for f, err := range NewEncoder(config).EncodeStream(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		done, _ := dec.AddFrame(f.Dots)
+		if lt := dec.fountain; lt != nil {
+			for i, b := range lt.blocks[:dec.stream.next] {
+				if b != nil {
+					t.Fatalf("block %d still held after it was written", i)
+				}
+			}
+		}
+		if done {
+			break
+		}
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("streamed %d bytes, want %d", out.Len(), len(data))
+	}
+}
+
+func TestStreamDecoderVerifyEncrypted(t *testing.T) {
+	pub, priv := testKey(6)
+	config := DefaultConfig()
+	config.Preamble = true
+	enc := NewEncoder(config)
+	enc.Sign(priv)
+	enc.SetPassphrase("pw")
+	frames := mustEncode(t, enc, bytes.Repeat([]byte("sealed "), 10))
+
+	// Without the passphrase nothing can be written, but the signature
+	// covers the ciphertext and still checks out.
+	var out bytes.Buffer
+	dec := NewStreamDecoder(config, &out)
+	if err := dec.Verify(pub); err != ErrIncompleteData {
+		t.Errorf("Verify before any frame = %v, want ErrIncompleteData", err)
+	}
+	var err error
+	for _, f := range frames {
+		_, err = dec.AddFrame(f.Dots)
+	}
+	if err != ErrKeyRequired || out.Len() != 0 {
+		t.Fatalf("last AddFrame = %v with %d bytes written, want ErrKeyRequired and none", err, out.Len())
+	}
+	if err := dec.Verify(pub); err != nil {
+		t.Errorf("Verify without passphrase = %v, want nil", err)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func TestStreamDecoderWriteError(t *testing.T) {
+	config := DefaultConfig()
+	frames := mustEncode(t, NewEncoder(config), bytes.Repeat([]byte("w"), 50))
+	dec := NewStreamDecoder(config, failWriter{})
+	for _, f := range frames {
+		if _, err := dec.AddFrame(f.Dots); err != io.ErrClosedPipe {
+			t.Fatalf("AddFrame = %v, want io.ErrClosedPipe", err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package dotbeam
+
+import "slices"
+
+// Per-index majority voting.
+//
+// A single camera read of a frame can misclassify a few dots. With
+// Config.Votes set, the decoder buckets reads by the frame index in their
+// raw header and holds them back. Once a bucket holds Votes reads, every
+// dot takes the value most reads agree on (ties go to the lower value) and
+// the voted frame is decoded as usual. A bucket keeps only the latest Votes
+// reads, so a frame that never reaches Config.Agreement holds bounded
+// memory and old misreads age out. If the voted header names a different
+// frame, the reads were mis-bucketed and the bucket restarts. Later reads
+// of a committed frame are treated as plain duplicates.
+
+// vote adds a read to its frame's bucket and returns the voted frame bytes
+// once the bucket is ready. It returns nil, nil while the vote is pending.
+func (d *Decoder) vote(dots []Dot) ([]byte, error) {
+	h, _, err := d.config.parseHeader(d.dotsToBytes(dots))
+	if err != nil {
+		return nil, err
+	}
+	index := h.index
+	if _, done := d.confidence[index]; done {
+		return d.readFrame(dots)
+	}
+
+	reads := d.reads[index]
+	if len(reads) > 0 && len(reads[0]) != len(dots) {
+		return nil, ErrInvalidFrame
+	}
+	values := make([]uint8, len(dots))
+	for i, dot := range dots {
+		values[i] = dot.Value
+	}
+	reads = append(reads, values)
+	if len(reads) > d.config.Votes {
+		reads = slices.Delete(reads, 0, len(reads)-d.config.Votes)
+	}
+	if d.reads == nil {
+		d.reads = make(map[int][][]uint8)
+	}
+	d.reads[index] = reads
+	if len(reads) < d.config.Votes {
+		return nil, nil
+	}
+
+	voted, confidence := majority(reads, dots)
+	if confidence < d.config.Agreement {
+		return nil, nil
+	}
+	data, err := d.readFrame(voted)
+	if err != nil {
+		return nil, err
+	}
+	if h, _, err := d.config.parseHeader(data); err != nil || h.index != index {
+		delete(d.reads, index)
+		return nil, ErrInvalidFrame
+	}
+
+	delete(d.reads, index)
+	if d.confidence == nil {
+		d.confidence = make(map[int]float64)
+	}
+	d.confidence[index] = confidence
+	return data, nil
+}
+
+// majority returns the per-dot majority of reads, positioned like dots,
+// and the mean share of reads that agree with each winning value. Each
+// voted dot's Confidence is its own share, so split votes can become
+// Reed-Solomon erasures.
+func majority(reads [][]uint8, dots []Dot) ([]Dot, float64) {
+	voted := append([]Dot(nil), dots...)
+	agree := 0
+	for i := range voted {
+		var counts [256]int
+		best := uint8(0)
+		for _, r := range reads {
+			v := r[i]
+			counts[v]++
+			if counts[v] > counts[best] || counts[v] == counts[best] && v < best {
+				best = v
+			}
+		}
+		voted[i].Value = best
+		voted[i].Confidence = float64(counts[best]) / float64(len(reads))
+		agree += counts[best]
+	}
+	return voted, float64(agree) / float64(len(reads)*len(voted))
+}
+
+// VoteConfidence returns the confidence of the committed vote for a frame
+// index (for fountain frames, a seed): the mean share of reads that agreed
+// with each voted dot, from 1/Votes up to 1.0. ok is false until the vote
+// commits or if Config.Votes is not set.
+func (d *Decoder) VoteConfidence(index int) (confidence float64, ok bool) {
+	confidence, ok = d.confidence[index]
+	return confidence, ok
+}
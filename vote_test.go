@@ -0,0 +1,170 @@
+package dotbeam
+
+import (
+	"bytes"
+	"testing"
+)
+
+// misread returns a copy of dots with dot i misclassified.
+func misread(dots []Dot, i int) []Dot {
+	out := append([]Dot(nil), dots...)
+	out[i].Value ^= 0x02
+	return out
+}
+
+func TestVoteOutvotesBadFirstRead(t *testing.T) {
+	config := DefaultConfig()
+	config.Votes = 3
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := []byte("first read is wrong")
+	f := mustEncode(t, enc, data)[0]
+	bad := misread(f.Dots, 10)
+
+	for i, dots := range [][]Dot{bad, f.Dots, f.Dots} {
+		done, err := dec.AddFrame(dots)
+		if err != nil {
+			t.Fatalf("AddFrame(%d) error: %v", i, err)
+		}
+		if want := i == 2; done != want {
+			t.Fatalf("AddFrame(%d) done = %v, want %v", i, done, want)
+		}
+	}
+
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatalf("got %q, want prefix %q", got, data)
+	}
+
+	conf, ok := dec.VoteConfidence(0)
+	want := 1 - 1/float64(3*len(f.Dots))
+	if !ok || conf != want {
+		t.Fatalf("VoteConfidence(0) = %v, %v; want %v, true", conf, ok, want)
+	}
+}
+
+func TestVoteRecoversFromIndividuallyBadReads(t *testing.T) {
+	config := DefaultConfig()
+	config.Checksum = true
+	config.Votes = 3
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := []byte("no single read passes the checksum")
+	frames := mustEncode(t, enc, data)
+	for _, f := range frames {
+		for _, i := range []int{3, 17, 40} {
+			if _, err := dec.AddFrame(misread(f.Dots, i)); err != nil {
+				t.Fatalf("AddFrame(%d) error: %v", f.Index, err)
+			}
+		}
+	}
+
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatalf("got %q, want prefix %q", got, data)
+	}
+}
+
+func TestVoteAgreementThreshold(t *testing.T) {
+	config := DefaultConfig()
+	config.Votes = 2
+	config.Agreement = 0.995
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	f := mustEncode(t, enc, []byte("agree"))[0]
+	dec.AddFrame(misread(f.Dots, 20))
+	dec.AddFrame(f.Dots)
+	if _, ok := dec.VoteConfidence(0); ok {
+		t.Fatal("vote committed below the agreement threshold")
+	}
+
+	// More clean reads raise the agreement until the vote commits.
+	for range 2 {
+		dec.AddFrame(f.Dots)
+	}
+	if conf, ok := dec.VoteConfidence(0); !ok || conf < config.Agreement {
+		t.Fatalf("VoteConfidence(0) = %v, %v; want >= %v", conf, ok, config.Agreement)
+	}
+	if dec.Progress() != 1.0 {
+		t.Fatalf("expected progress 1.0, got %f", dec.Progress())
+	}
+}
+
+func TestVoteReadsBounded(t *testing.T) {
+	config := DefaultConfig()
+	config.Votes = 3
+	config.Agreement = 1
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	// Every read misreads some dot, so the vote never reaches full agreement.
+	f := mustEncode(t, enc, []byte("never unanimous"))[0]
+	for i := range 100 {
+		if _, err := dec.AddFrame(misread(f.Dots, 10+i%40)); err != nil {
+			t.Fatalf("AddFrame(%d) error: %v", i, err)
+		}
+		if n := len(dec.reads[0]); n > config.Votes {
+			t.Fatalf("after %d reads, %d held for frame 0; want at most %d", i+1, n, config.Votes)
+		}
+	}
+	if _, ok := dec.VoteConfidence(0); ok {
+		t.Fatal("vote committed without full agreement")
+	}
+}
+
+func TestVoteFountain(t *testing.T) {
+	config := DefaultConfig()
+	config.UseFountain = true
+	config.Votes = 2
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	data := bytes.Repeat([]byte("voted fountain "), 6)
+	frames := mustEncode(t, enc, data)
+	for _, f := range frames {
+		dec.AddFrame(f.Dots)
+		dec.AddFrame(f.Dots)
+	}
+	got, err := dec.Data()
+	if err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !bytes.HasPrefix(got, data) {
+		t.Fatalf("got %q, want prefix %q", got, data)
+	}
+}
+
+func TestVoteReset(t *testing.T) {
+	config := DefaultConfig()
+	config.Votes = 2
+	enc := NewEncoder(config)
+	dec := NewDecoder(config)
+
+	f := mustEncode(t, enc, []byte("reset"))[0]
+	dec.AddFrame(f.Dots)
+	dec.Reset()
+	dec.AddFrame(f.Dots)
+	if _, ok := dec.VoteConfidence(0); ok {
+		t.Fatal("Reset should discard pending reads")
+	}
+}
+
+func TestVoteInvalidConfig(t *testing.T) {
+	for _, c := range []Config{
+		{Rings: 4, BitsPerDot: 3, Votes: -1},
+		{Rings: 4, BitsPerDot: 3, Votes: 3, Agreement: 1.5},
+	} {
+		if _, err := NewEncoder(c).Encode([]byte("x")); err != ErrInvalidConfig {
+			t.Fatalf("Encode with %+v: err = %v, want ErrInvalidConfig", c, err)
+		}
+	}
+}
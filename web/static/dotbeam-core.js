@@ -34,11 +34,17 @@
   // Anchors: 3 dots at radius 0.82, placed at 270deg, 30deg, 150deg
   //          (top-center, bottom-right, bottom-left in screen coords).
   //
+  // Cue:     1 small dot at radius 0.92, 300deg (30deg clockwise of the
+  //          270deg anchor). The anchor triangle alone looks the same
+  //          mirrored; the cue does not.
+  //
   // Rings:   Ring N (1-indexed) has N*6 dots.
   //          Radii are evenly distributed from 0.22 to 0.70.
 
   var ANCHOR_RADIUS = 0.82;
   var ANCHOR_ANGLES_DEG = [270, 30, 150];
+  var CUE_RADIUS = 0.92;
+  var CUE_ANGLE_DEG = 300;
   var RING_RADIUS_MIN = 0.22;
   var RING_RADIUS_MAX = 0.70;
 
@@ -52,6 +58,7 @@
    * @param {object} [config] — optional, defaults to defaultConfig()
    * @returns {{
    *   anchors: Array<{x: number, y: number, angle: number}>,
+   *   cue: {x: number, y: number, angle: number},
    *   rings: Array<{
    *     ringIndex: number,
    *     radius: number,
@@ -77,6 +84,14 @@
       });
     }
 
+    // ── Orientation cue ──────────────────────────────────────────────
+    var cRad = degToRad(CUE_ANGLE_DEG);
+    var cue = {
+      x: Math.cos(cRad) * CUE_RADIUS,
+      y: -Math.sin(cRad) * CUE_RADIUS,
+      angle: cRad,
+    };
+
     // ── Data rings ───────────────────────────────────────────────────
     var rings = [];
     var totalDots = 0;
@@ -119,6 +134,7 @@
 
     return {
       anchors: anchors,
+      cue: cue,
       rings: rings,
       totalDots: totalDots,
       config: config,
@@ -134,6 +150,8 @@
     // Expose constants for external use
     ANCHOR_RADIUS: ANCHOR_RADIUS,
     ANCHOR_ANGLES_DEG: ANCHOR_ANGLES_DEG,
+    CUE_RADIUS: CUE_RADIUS,
+    CUE_ANGLE_DEG: CUE_ANGLE_DEG,
     RING_RADIUS_MIN: RING_RADIUS_MIN,
     RING_RADIUS_MAX: RING_RADIUS_MAX,
   };
@@ -8,6 +8,7 @@
   var BG_COLOR = "#0a0a1a";
   var DATA_DOT_RADIUS_FACTOR = 0.06; // relative to canvas half-size (large for camera readability)
   var ANCHOR_DOT_RADIUS_FACTOR = 0.065; // slightly larger than data dots
+  var CUE_DOT_RADIUS_FACTOR = 0.04; // small enough not to pass for an anchor
   var RING_GUIDE_OPACITY = 0.04;
   var TRANSITION_MS = 0; // instant frame changes (no blending = clean colors for scanner)
   var BREATHING_PERIOD_MS = 3000;
@@ -74,6 +75,7 @@
    * Expected shape:
    * {
    *   config: { rings, bitsPerDot, fps },
+   *   colors: ["#rrggbb", ...],   // optional, defaults to DotbeamCore.colors
    *   frames: [
    *     { dots: [colorIndex, colorIndex, ...] },
    *     ...
@@ -89,6 +91,12 @@
     // Pre-compute color arrays for each frame
     this._frameColorArrays = [];
     var palette = DotbeamCore.colors;
+    if (apiData.colors && apiData.colors.length) {
+      palette = apiData.colors.map(function (hex) {
+        var n = parseInt(hex.slice(1), 16);
+        return { r: (n >> 16) & 0xff, g: (n >> 8) & 0xff, b: n & 0xff };
+      });
+    }
     for (var f = 0; f < this._frames.length; f++) {
       var dots = this._frames[f].dots || [];
       var colors = [];
@@ -272,7 +280,7 @@
       }
     }
 
-    // ── Anchor dots (drawn last, on top) ─────────────────────────────
+    // ── Anchor dots and orientation cue (drawn last, on top) ─────────
     if (this._layoutData) {
       var white = { r: 255, g: 255, b: 255 };
       for (var ai = 0; ai < this._layoutData.anchors.length; ai++) {
@@ -287,6 +295,17 @@
         ctx.arc(ax, ay, ar, 0, 2 * Math.PI);
         ctx.fill();
       }
+
+      var cue = this._layoutData.cue;
+      ctx.beginPath();
+      ctx.arc(
+        cx + cue.x * scale,
+        cy + cue.y * scale,
+        CUE_DOT_RADIUS_FACTOR * scale * breathScale,
+        0,
+        2 * Math.PI
+      );
+      ctx.fill();
     }
   };
 
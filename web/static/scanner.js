@@ -6,12 +6,23 @@
 (function () {
   "use strict";
 
-  var palette = DotbeamCore.colors;
-
-  // Pre-compute palette hues for fast matching.
-  var paletteHues = [];
-  for (var pi = 0; pi < palette.length; pi++) {
-    paletteHues.push(rgbToHue(palette[pi].r, palette[pi].g, palette[pi].b));
+  /**
+   * Build a palette to match against from "#rrggbb" strings, such as the
+   * demo's /api/frames colors, with hues pre-computed for fast matching.
+   * Falls back to DotbeamCore.colors.
+   */
+  function makePalette(hexColors) {
+    var colors = DotbeamCore.colors;
+    if (hexColors && hexColors.length) {
+      colors = hexColors.map(function (hex) {
+        var n = parseInt(hex.slice(1), 16);
+        return { r: (n >> 16) & 0xff, g: (n >> 8) & 0xff, b: n & 0xff };
+      });
+    }
+    var hues = colors.map(function (c) {
+      return rgbToHue(c.r, c.g, c.b);
+    });
+    return { colors: colors, hues: hues };
   }
 
   // ── Helpers ────────────────────────────────────────────────────────
@@ -50,11 +61,12 @@
   }
 
   /**
-   * Return palette index of the nearest color.
+   * Return index of the nearest color in pal (from makePalette).
    * Uses hue-based matching (robust to camera exposure/white-balance shifts)
    * with RGB fallback for achromatic or very dark samples.
    */
-  function matchColor(r, g, b) {
+  function matchColor(r, g, b, pal) {
+    var palette = pal.colors;
     var max = Math.max(r, g, b);
     var min = Math.min(r, g, b);
     var sat = max === 0 ? 0 : (max - min) / max;
@@ -67,7 +79,7 @@
         var bestIdx = 0;
         var bestDist = Infinity;
         for (var i = 0; i < palette.length; i++) {
-          var hd = hueDist(sampleHue, paletteHues[i]);
+          var hd = hueDist(sampleHue, pal.hues[i]);
           if (hd < bestDist) {
             bestDist = hd;
             bestIdx = i;
@@ -110,6 +122,42 @@
 
   // ── Blob detection for anchors ─────────────────────────────────────
 
+  /**
+   * Estimate the level white reaches in each channel: the value the
+   * brightest WHITE_SHARE of pixels reach, at least MIN_WHITE_LEVEL.
+   * The anchors are white and every saturated data dot maxes out some
+   * channel, so under a white-balance or exposure error it follows the
+   * anchors.
+   */
+  function whiteLevel(imageData, width, height) {
+    var WHITE_SHARE = 0.002;
+    var MIN_WHITE_LEVEL = 64;
+    var data = imageData.data;
+    var hist = [new Uint32Array(256), new Uint32Array(256), new Uint32Array(256)];
+    var n = 0;
+    for (var y = 0; y < height; y += 2) {
+      for (var x = 0; x < width; x += 2) {
+        var idx = (y * width + x) * 4;
+        hist[0][data[idx]]++;
+        hist[1][data[idx + 1]]++;
+        hist[2][data[idx + 2]]++;
+        n++;
+      }
+    }
+
+    var level = [];
+    for (var ch = 0; ch < 3; ch++) {
+      var v = 255;
+      var count = 0;
+      for (; v > MIN_WHITE_LEVEL; v--) {
+        count += hist[ch][v];
+        if (count >= WHITE_SHARE * n) break;
+      }
+      level.push(v);
+    }
+    return level;
+  }
+
   /**
    * Two-pass bright-white blob finder with saturation filtering.
    *
@@ -118,13 +166,20 @@
    *         (low saturation) — not just a bright coloured area that happens
    *         to have all channels above threshold through the camera.
    *
+   * Both passes measure against the capture's white level (whiteLevel)
+   * rather than pure white, so a tinted or dim capture keeps its anchors.
+   *
    * Returns an array of {x, y, size} blobs sorted by size descending.
    * Large blobs (likely screen glare) are capped / discarded.
    */
   function findWhiteBlobs(imageData, width, height) {
     var data = imageData.data;
-    var THRESHOLD = 200; // aggressive — only truly white areas pass
+    var THRESHOLD = 200; // of 255 at the white level — only truly white areas pass
     var CELL_SIZE = 8;
+    var level = whiteLevel(imageData, width, height);
+    var thrR = Math.floor((level[0] * THRESHOLD) / 255);
+    var thrG = Math.floor((level[1] * THRESHOLD) / 255);
+    var thrB = Math.floor((level[2] * THRESHOLD) / 255);
 
     // Maximum blob size in grid cells.  A real anchor dot at typical viewing
     // distance produces ≤ 30 cells.  Blobs bigger than this are screen glare.
@@ -141,7 +196,7 @@
         var r = data[idx];
         var g = data[idx + 1];
         var b = data[idx + 2];
-        if (r > THRESHOLD && g > THRESHOLD && b > THRESHOLD) {
+        if (r > thrR && g > thrG && b > thrB) {
           var gx = Math.floor(x / CELL_SIZE);
           var gy = Math.floor(y / CELL_SIZE);
           grid[gy * gridW + gx] = 1;
@@ -211,9 +266,9 @@
             }
           }
           if (cnt > 0) {
-            var avgR = sumR / cnt;
-            var avgG = sumG / cnt;
-            var avgB = sumB / cnt;
+            var avgR = (sumR / cnt) * 255 / level[0];
+            var avgG = (sumG / cnt) * 255 / level[1];
+            var avgB = (sumB / cnt) * 255 / level[2];
             var cMax = Math.max(avgR, avgG, avgB);
             var cMin = Math.min(avgR, avgG, avgB);
             var cSat = cMax > 0 ? (cMax - cMin) / cMax : 0;
@@ -275,9 +330,12 @@
    *   30deg anchor  → (+0.71, -0.41) → top-right
    *   150deg anchor → (-0.71, -0.41) → top-left
    *
-   * The bottommost blob (largest screen Y) is the 270deg anchor.
+   * The orientation cue, 30deg from the 270deg anchor, identifies that
+   * anchor and tells a mirror image apart. Without a cue the bottommost
+   * blob (largest screen Y) is taken as the 270deg anchor.
    *
-   * Returns { center, scale, rotation } or null if detection failed.
+   * Returns { center, scale, rotation, mirrored } or null if detection
+   * failed.
    */
   /**
    * Maximum center brightness for a valid anchor triple.
@@ -288,6 +346,82 @@
   var MAX_CENTER_BRIGHTNESS = 80;
   var MIN_ANCHOR_BRIGHTNESS = 100;
 
+  /** Farthest a cue blob may sit from where a labelling predicts it. */
+  var MAX_CUE_OFFSET = 0.2; // normalized units
+
+  /** Every way to label an anchor triple as the 270/30/150deg anchors. */
+  var ANCHOR_ORDERS = [
+    [0, 1, 2], [1, 2, 0], [2, 0, 1],
+    [0, 2, 1], [1, 0, 2], [2, 1, 0],
+  ];
+
+  /**
+   * Solve for (a, b, c) such that a*x + b*y + c = v at three points.
+   */
+  function solveAffine(pts, v) {
+    var x0 = pts[0].x, y0 = pts[0].y;
+    var x1 = pts[1].x, y1 = pts[1].y;
+    var x2 = pts[2].x, y2 = pts[2].y;
+    var det = x0 * (y1 - y2) - y0 * (x1 - x2) + (x1 * y2 - x2 * y1);
+    return [
+      (v[0] * (y1 - y2) - y0 * (v[1] - v[2]) + (v[1] * y2 - v[2] * y1)) / det,
+      (x0 * (v[1] - v[2]) - v[0] * (x1 - x2) + (x1 * v[2] - x2 * v[1])) / det,
+      (x0 * (y1 * v[2] - y2 * v[1]) - y0 * (x1 * v[2] - x2 * v[1]) +
+        v[0] * (x1 * y2 - x2 * y1)) / det,
+    ];
+  }
+
+  /**
+   * Look for the orientation cue beside an anchor triple. Each way of
+   * labelling the triple fixes an affine map from the layout, and the
+   * labelling that finds a blob where it puts the cue wins.
+   *
+   * Returns { anchors, mirrored } with anchors in layout order, or null
+   * if no cue is visible.
+   */
+  function findCue(blobs, triple, scale) {
+    var ref = DotbeamCore.layout();
+    var best = MAX_CUE_OFFSET * scale;
+    var found = null;
+    for (var o = 0; o < ANCHOR_ORDERS.length; o++) {
+      var order = ANCHOR_ORDERS[o];
+      var labelled = [triple[order[0]], triple[order[1]], triple[order[2]]];
+      var mx = solveAffine(ref.anchors, [labelled[0].x, labelled[1].x, labelled[2].x]);
+      var my = solveAffine(ref.anchors, [labelled[0].y, labelled[1].y, labelled[2].y]);
+      var predicted = {
+        x: mx[0] * ref.cue.x + mx[1] * ref.cue.y + mx[2],
+        y: my[0] * ref.cue.x + my[1] * ref.cue.y + my[2],
+      };
+      for (var i = 0; i < blobs.length; i++) {
+        if (triple.indexOf(blobs[i]) >= 0) continue;
+        var d = dist(blobs[i], predicted);
+        if (d < best) {
+          best = d;
+          found = {
+            anchors: labelled,
+            // A mirror image reverses the map's orientation.
+            mirrored: mx[0] * my[1] - mx[1] * my[0] < 0,
+          };
+        }
+      }
+    }
+    return found;
+  }
+
+  /**
+   * Map a normalized layout point to camera pixels. A mirrored transform
+   * flips x before rotating.
+   */
+  function toCamera(transform, x, y) {
+    if (transform.mirrored) x = -x;
+    var cosR = Math.cos(transform.rotation);
+    var sinR = Math.sin(transform.rotation);
+    return {
+      x: transform.center.x + (x * cosR - y * sinR) * transform.scale,
+      y: transform.center.y + (x * sinR + y * cosR) * transform.scale,
+    };
+  }
+
   function deriveTransform(blobs, imageData, imgWidth) {
     if (blobs.length < 3) return null;
 
@@ -323,19 +457,25 @@
 
           var scale = avgDist / DotbeamCore.ANCHOR_RADIUS;
 
-          // The 270deg anchor is the bottommost blob (largest Y in screen coords).
-          var bottomIdx = 0;
-          for (var m = 1; m < 3; m++) {
-            if (candidates[m].y > candidates[bottomIdx].y) {
-              bottomIdx = m;
+          // The cue names the 270deg anchor; without one, take the
+          // bottommost blob (largest Y in screen coords).
+          var cue = findCue(blobs, candidates, scale);
+          var a0;
+          if (cue) {
+            a0 = cue.anchors[0];
+          } else {
+            a0 = candidates[0];
+            for (var m = 1; m < 3; m++) {
+              if (candidates[m].y > a0.y) {
+                a0 = candidates[m];
+              }
             }
           }
 
-          // Compute actual angle from center to the bottom blob in camera coords.
-          var bottomBlob = candidates[bottomIdx];
+          // Compute actual angle from center to that anchor in camera coords.
           var detectedAngle = Math.atan2(
-            bottomBlob.y - center.y,
-            bottomBlob.x - center.x
+            a0.y - center.y,
+            a0.x - center.x
           );
           // In screen coords, the 270deg anchor is at (0, +0.82) → angle PI/2
           var expectedAngle = Math.PI / 2;
@@ -349,6 +489,7 @@
             center: center,
             scale: scale,
             rotation: rotation,
+            mirrored: cue ? cue.mirrored : false,
             anchors: candidates,
           };
         }
@@ -447,10 +588,8 @@
    * Also populates result.debugRgb with raw+corrected RGB for the
    * first 6 dots (ring 1) for diagnostic display.
    */
-  function sampleDots(imageData, width, transform, layoutData, wbGain) {
-    var center = transform.center;
+  function sampleDots(imageData, width, transform, layoutData, wbGain, pal) {
     var scale = transform.scale;
-    var rotation = transform.rotation;
 
     var allDots = [];
     for (var ri = 0; ri < layoutData.rings.length; ri++) {
@@ -465,19 +604,15 @@
     // (dot rendered at 0.06 * scale ≈ 6% of scale; search at 3%).
     var searchRadius = Math.max(3, Math.floor(scale * 0.03));
     var searchStep = Math.max(2, Math.floor(searchRadius / 2));
-    var cosR = Math.cos(rotation);
-    var sinR = Math.sin(rotation);
-
     var results = [];
     results.debugRgb = []; // first 6 dots' raw + corrected RGB
 
     for (var i = 0; i < allDots.length; i++) {
       var dot = allDots[i];
 
-      var rx = dot.x * cosR - dot.y * sinR;
-      var ry = dot.x * sinR + dot.y * cosR;
-      var px = Math.round(center.x + rx * scale);
-      var py = Math.round(center.y + ry * scale);
+      var pos = toCamera(transform, dot.x, dot.y);
+      var px = Math.round(pos.x);
+      var py = Math.round(pos.y);
 
       // Peak-seeking: search a small grid around the expected position
       // and pick the brightest sample (dot centers are always brighter
@@ -506,11 +641,11 @@
         results.debugRgb.push({
           raw: raw,
           corrected: { r: cr, g: cg, b: cb },
-          matched: matchColor(cr, cg, cb),
+          matched: matchColor(cr, cg, cb, pal),
         });
       }
 
-      results.push(matchColor(cr, cg, cb));
+      results.push(matchColor(cr, cg, cb, pal));
     }
 
     return results;
@@ -781,6 +916,8 @@
    * @param {HTMLVideoElement} videoElement — displays the camera feed
    * @param {HTMLCanvasElement} overlayCanvas — drawn over the video for
    *        progress ring and other UI
+   * @param {object} [options] — onProgress, onComplete, onError callbacks
+   *        and colors, the sender's palette as "#rrggbb" strings
    */
   function DotbeamScanner(videoElement, overlayCanvas, options) {
     this._video = videoElement;
@@ -801,6 +938,9 @@
 
     // Callbacks (can be set via options or .onProgress()/.onComplete()/.onError())
     var opts = options || {};
+
+    // Colors the sender uses, as "#rrggbb" strings (default DotbeamCore.colors)
+    this._palette = makePalette(opts.colors);
     this._onProgress = opts.onProgress || null;
     this._onComplete = opts.onComplete || null;
     this._onError = opts.onError || null;
@@ -950,12 +1090,14 @@
 
       if (freshTransform && this._cachedTransform) {
         // Accept the fresh transform only if it agrees with the cached one
-        // (center within 15% of scale, scale within 20%, rotation within 15°).
+        // (center within 15% of scale, scale within 20%, rotation within 15°,
+        // same mirroring).
         var cdx = freshTransform.center.x - this._cachedTransform.center.x;
         var cdy = freshTransform.center.y - this._cachedTransform.center.y;
         var centerDrift = Math.sqrt(cdx * cdx + cdy * cdy);
         var scaleDrift = Math.abs(freshTransform.scale - this._cachedTransform.scale);
         var rotDrift = Math.abs(freshTransform.rotation - this._cachedTransform.rotation);
+        if (rotDrift > Math.PI) rotDrift = 2 * Math.PI - rotDrift;
 
         var maxCenterDrift = this._cachedTransform.scale * 0.15;
         var maxScaleDrift = this._cachedTransform.scale * 0.20;
@@ -963,7 +1105,8 @@
 
         if (centerDrift < maxCenterDrift &&
             scaleDrift < maxScaleDrift &&
-            rotDrift < maxRotDrift) {
+            rotDrift < maxRotDrift &&
+            freshTransform.mirrored === this._cachedTransform.mirrored) {
           // Fresh transform is consistent — update the cache
           transform = freshTransform;
           this._cachedTransform = freshTransform;
@@ -1025,7 +1168,7 @@
 
     // Sample dot colors with WB correction
     var dotValues = sampleDots(
-      imageData, vw, transform, this._layoutData, wbGain
+      imageData, vw, transform, this._layoutData, wbGain, this._palette
     );
     this._dbgDotRgb = dotValues.debugRgb || null;
 
@@ -1037,16 +1180,13 @@
         allDots.push(ring.dots[di]);
       }
     }
-    var cosR = Math.cos(transform.rotation);
-    var sinR = Math.sin(transform.rotation);
     this._dbgDotPositions = [];
     for (var di2 = 0; di2 < allDots.length; di2++) {
       var dot = allDots[di2];
-      var rx = dot.x * cosR - dot.y * sinR;
-      var ry = dot.x * sinR + dot.y * cosR;
+      var pos = toCamera(transform, dot.x, dot.y);
       this._dbgDotPositions.push({
-        vx: transform.center.x + rx * transform.scale,
-        vy: transform.center.y + ry * transform.scale,
+        vx: pos.x,
+        vy: pos.y,
         colorIdx: dotValues[di2],
       });
     }
@@ -1233,7 +1373,8 @@
       );
       debugLines.push("scale: " + Math.round(this._dbgTransform.scale));
       debugLines.push(
-        "rot: " + (this._dbgTransform.rotation * 180 / Math.PI).toFixed(1) + "°"
+        "rot: " + (this._dbgTransform.rotation * 180 / Math.PI).toFixed(1) + "°" +
+          (this._dbgTransform.mirrored ? " mirrored" : "")
       );
     }
     if (this._dbgCenterSample) {
@@ -1346,7 +1487,7 @@
       for (var dpi = 0; dpi < this._dbgDotPositions.length; dpi++) {
         var dp = this._dbgDotPositions[dpi];
         var dsp = self._videoToScreen(dp.vx, dp.vy);
-        var col = palette[dp.colorIdx];
+        var col = this._palette.colors[dp.colorIdx];
         ctx.fillStyle =
           "rgba(" + col.r + "," + col.g + "," + col.b + ",0.8)";
         ctx.beginPath();